@@ -0,0 +1,492 @@
+package eforth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+The host compiler behind AddWord.  It compiles a colon definition from go
+the way : and ; would, without running the forth interpreter.  Words are
+looked up in the name dictionary, so later definitions hide earlier ones,
+numbers are compiled with doLIT, 2 doLITs for doubles or doFLIT, and the
+immediate words of the compiler are done here, anything else that is
+IMMEDIATE can only run in forth and is an error.
+*/
+
+// words compiled with an inline string by the host compiler
+var hostStrings = map[string]string{
+	`."`:     `."|`,
+	`$"`:     `$"|`,
+	`S"`:     `S"|`,
+	`S\"`:    `S"|`,
+	`ABORT"`: `abort"`,
+}
+
+// source of a definition being compiled by AddWord
+type hostSource struct {
+	text string
+	pos  int
+	line int
+}
+
+// return the next blank delimited word or "" at the end of the text
+func (s *hostSource) word() string {
+	for s.pos < len(s.text) && s.text[s.pos] <= ' ' {
+		if s.text[s.pos] == '\n' {
+			s.line += 1
+		}
+		s.pos += 1
+	}
+	i := s.pos
+	for s.pos < len(s.text) && s.text[s.pos] > ' ' {
+		s.pos += 1
+	}
+	return s.text[i:s.pos]
+}
+
+// return the text up to the next c, skipping the blank after the last word
+func (s *hostSource) parse(c byte) (string, bool) {
+	if s.pos < len(s.text) {
+		s.pos += 1
+	}
+	i := strings.IndexByte(s.text[min(s.pos, len(s.text)):], c)
+	if i < 0 {
+		s.pos = len(s.text)
+		return "", false
+	}
+	t := s.text[s.pos : s.pos+i]
+	s.line += strings.Count(t, "\n")
+	s.pos += i + 1
+	return t, true
+}
+
+// return the text up to the next " translating the escapes of S\" in it
+func (s *hostSource) parseEscaped() (string, bool, error) {
+	if s.pos < len(s.text) {
+		s.pos += 1
+	}
+	t, used, err := unescape(s.text[s.pos:])
+	if err != nil {
+		return "", true, err
+	}
+	s.line += strings.Count(s.text[s.pos:s.pos+used], "\n")
+	s.pos += used
+	return t, used > 0 && s.text[s.pos-1] == '"', nil
+}
+
+// skip a ( comment, which may have ( comments nested in it
+func (s *hostSource) comment() bool {
+	depth := 1
+	for s.pos < len(s.text) {
+		switch s.text[s.pos] {
+		case '(':
+			depth += 1
+		case ')':
+			depth -= 1
+		case '\n':
+			s.line += 1
+		}
+		s.pos += 1
+		if depth == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// skip to the end of the line
+func (s *hostSource) toEOL() {
+	for s.pos < len(s.text) && s.text[s.pos] != '\n' {
+		s.pos += 1
+	}
+}
+
+// an unresolved branch or a loop start on the control stack
+type hostControl struct {
+	kind string // IF BEGIN FOR DO CASE OF or ENDOF
+	a    uint16 // address of the branch cell to resolve or to branch back to
+}
+
+// a colon definition being compiled by AddWord
+type hostDef struct {
+	f       *Forth
+	start   uint16
+	code    []byte
+	control []hostControl
+}
+
+func (d *hostDef) here() uint16 {
+	return d.start + uint16(len(d.code))
+}
+
+func (d *hostDef) comma(v uint16) {
+	d.code = binary.LittleEndian.AppendUint16(d.code, v)
+}
+
+func (d *hostDef) compile(w string) {
+	ca, _ := d.f.Addr(w)
+	d.comma(ca)
+}
+
+// compile a branch word with an unresolved address and return the address
+// of its operand
+func (d *hostDef) branch(w string) uint16 {
+	d.compile(w)
+	a := d.here()
+	d.comma(0)
+	return a
+}
+
+func (d *hostDef) resolve(a uint16) {
+	binary.LittleEndian.PutUint16(d.code[a-d.start:], d.here())
+}
+
+func (d *hostDef) push(kind string, a uint16) {
+	d.control = append(d.control, hostControl{kind, a})
+}
+
+// pop the top of the control stack if it is one of kinds
+func (d *hostDef) pop(w string, kinds ...string) (uint16, error) {
+	n := len(d.control)
+	if n > 0 {
+		for _, k := range kinds {
+			if d.control[n-1].kind == k {
+				a := d.control[n-1].a
+				d.control = d.control[:n-1]
+				return a, nil
+			}
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("%s without %s", w, kinds[0]))
+}
+
+// compile a counted string after the word w
+func (d *hostDef) compileString(w, s string) error {
+	if len(s) > 255 {
+		return errors.New(fmt.Sprintf("string of %s longer than 255", w))
+	}
+	d.compile(w)
+	d.code = append(d.code, byte(len(s)))
+	d.code = append(d.code, s...)
+	if len(d.code)%CELLL != 0 {
+		d.code = append(d.code, 0)
+	}
+	return nil
+}
+
+// compile the number w in the current BASE as a literal, returns false if
+// it is not a number
+func (d *hostDef) literal(w string) bool {
+	base := BASEE
+	if d.f.booted {
+		base = int(d.f.WordPtr(d.f.userAddr("BASE")))
+	}
+	if n, dpl, ok := parseNumber(w, base); ok {
+		if dpl < 0 {
+			d.compile("doLIT")
+			d.comma(uint16(n))
+		} else {
+			d.compile("doLIT")
+			d.comma(uint16(n))
+			d.compile("doLIT")
+			d.comma(uint16(n >> 16))
+		}
+		return true
+	}
+	if r, ok := parseFloat(w, true); ok {
+		d.compile("doFLIT")
+		d.code = binary.LittleEndian.AppendUint64(d.code, math.Float64bits(r))
+		return true
+	}
+	return false
+}
+
+/*
+Compile the control structure word w, returns false if w is not one.
+*/
+func (d *hostDef) structure(w string) (bool, error) {
+	var a uint16
+	var err error
+	switch w {
+	case "IF":
+		d.push("IF", d.branch("?branch"))
+	case "AHEAD":
+		d.push("IF", d.branch("branch"))
+	case "ELSE":
+		if a, err = d.pop(w, "IF"); err == nil {
+			d.push("IF", d.branch("branch"))
+			d.resolve(a)
+		}
+	case "THEN":
+		if a, err = d.pop(w, "IF"); err == nil {
+			d.resolve(a)
+		}
+	case "BEGIN":
+		d.push("BEGIN", d.here())
+	case "AGAIN", "UNTIL":
+		if a, err = d.pop(w, "BEGIN"); err == nil {
+			d.compile(map[string]string{"AGAIN": "branch", "UNTIL": "?branch"}[w])
+			d.comma(a)
+		}
+	case "WHILE":
+		// the IF goes under the BEGIN, so REPEAT is AGAIN THEN
+		if a, err = d.pop(w, "BEGIN"); err == nil {
+			d.push("IF", d.branch("?branch"))
+			d.push("BEGIN", a)
+		}
+	case "REPEAT":
+		if a, err = d.pop(w, "BEGIN"); err == nil {
+			d.compile("branch")
+			d.comma(a)
+			if a, err = d.pop(w, "IF"); err == nil {
+				d.resolve(a)
+			}
+		}
+	case "FOR":
+		d.compile(">R")
+		d.push("FOR", d.here())
+	case "AFT":
+		if _, err = d.pop(w, "FOR"); err == nil {
+			a = d.branch("branch")
+			d.push("FOR", d.here())
+			d.push("IF", a)
+		}
+	case "NEXT":
+		if a, err = d.pop(w, "FOR"); err == nil {
+			d.compile("next")
+			d.comma(a)
+		}
+	case "DO", "?DO":
+		d.push("DO", d.branch(map[string]string{"DO": "(do)", "?DO": "(?do)"}[w]))
+	case "LOOP", "+LOOP":
+		if a, err = d.pop(w, "DO"); err == nil {
+			d.compile(map[string]string{"LOOP": "(loop)", "+LOOP": "(+loop)"}[w])
+			d.comma(a + CELLL)
+			d.resolve(a)
+		}
+	case "CASE":
+		d.push("CASE", 0)
+	case "OF":
+		if len(d.control) == 0 || d.control[len(d.control)-1].kind != "CASE" &&
+			d.control[len(d.control)-1].kind != "ENDOF" {
+			return true, errors.New("OF without CASE")
+		}
+		d.compile("OVER")
+		d.compile("=")
+		d.push("OF", d.branch("?branch"))
+		d.compile("DROP")
+	case "ENDOF":
+		if a, err = d.pop(w, "OF"); err == nil {
+			d.push("ENDOF", d.branch("branch"))
+			d.resolve(a)
+		}
+	case "ENDCASE":
+		d.compile("DROP")
+		for err == nil {
+			if a, err = d.pop(w, "ENDOF", "CASE"); err == nil {
+				if a == 0 {
+					break
+				}
+				d.resolve(a)
+			}
+		}
+		if err != nil {
+			err = errors.New("ENDCASE without CASE")
+		}
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+/*
+Return the code address and lexicon bits of the word called name in the
+name dictionary built from go.
+*/
+func (f *Forth) findName(name string) (ca uint16, flags byte, ok bool) {
+	na := f._LAST
+	for i := 0; na != 0 && i < EM/CELLL; i++ {
+		l := f.Memory[na]
+		n := uint16(l & 0x1F)
+		if string(f.Memory[na+1:na+1+n]) == name {
+			return f.WordPtr(na - 2*CELLL), l &^ 0x1F, true
+		}
+		na = f.WordPtr(na - CELLL)
+	}
+	return 0, 0, false
+}
+
+/*
+Compile the colon definition in cdef.  The older form "doLIST name ... EXIT"
+is compiled like a listing, without an EXIT added and with IMMEDIATE words
+compiled rather than refused.  A number after doLIT is compiled as a plain
+cell in both forms.
+*/
+func (f *Forth) compileDefinition(cdef string) error {
+	s := &hostSource{text: cdef, line: 1}
+	name := ""
+	fail := func(msg string) error {
+		return errors.New(fmt.Sprintf("%s line %d: %s", name, s.line, msg))
+	}
+	colon := s.word()
+	if colon != ":" && colon != "doLIST" {
+		return fail(fmt.Sprintf(`definition starts with "%s" instead of :`, colon))
+	}
+	name = s.word()
+	switch {
+	case name == "":
+		return fail("missing name")
+	case len(name) > 31:
+		return fail(fmt.Sprintf(`name "%s" longer than 31`, name))
+	}
+	f.beginWord()
+	d := &hostDef{f: f, start: CODEE + CELLL*f.prims}
+	d.comma(CALLL)
+	d.compile("doLIST")
+	ended := false
+	flags := 0
+	for {
+		w := s.word()
+		if w == "" {
+			break
+		}
+		if ended {
+			if w != "IMMEDIATE" {
+				return fail(fmt.Sprintf(`"%s" after ;`, w))
+			}
+			flags = IMEDD
+			continue
+		}
+		switch w {
+		case "(":
+			if !s.comment() {
+				return fail("missing )")
+			}
+			continue
+		case `\`:
+			s.toEOL()
+			continue
+		case ";":
+			if colon != ":" {
+				return fail("; in a doLIST definition")
+			}
+			if len(d.control) > 0 {
+				return fail(fmt.Sprintf("%s without end", d.control[len(d.control)-1].kind))
+			}
+			d.compile("EXIT")
+			ended = true
+			continue
+		case "RECURSE":
+			d.comma(d.start)
+			continue
+		case "[COMPILE]", "POSTPONE":
+			n := s.word()
+			ca, fl, ok := f.findName(n)
+			if !ok {
+				return fail(fmt.Sprintf(`undefined word "%s" after %s`, n, w))
+			}
+			if w == "POSTPONE" && fl&IMEDD == 0 {
+				d.compile("COMPILE")
+			}
+			d.comma(ca)
+			continue
+		}
+		if hostStrings[w] != "" {
+			t, ok := "", false
+			if w == `S\"` {
+				var err error
+				if t, ok, err = s.parseEscaped(); err != nil {
+					return fail(err.Error())
+				}
+			} else {
+				t, ok = s.parse('"')
+			}
+			if !ok {
+				return fail(fmt.Sprintf(`missing " after %s`, w))
+			}
+			if err := d.compileString(hostStrings[w], t); err != nil {
+				return fail(err.Error())
+			}
+			continue
+		}
+		if ok, err := d.structure(w); ok {
+			if err != nil {
+				return fail(err.Error())
+			}
+			continue
+		}
+		if ca, fl, ok := f.findName(w); ok {
+			if fl&IMEDD != 0 && colon == ":" {
+				return fail(fmt.Sprintf(`immediate word "%s" cannot be compiled from go`, w))
+			}
+			d.comma(ca)
+			if w == "doLIT" {
+				n := s.word()
+				v, err := strconv.ParseInt(n, 0, 0)
+				if err != nil {
+					return fail(fmt.Sprintf(`"%s" after doLIT is not a number`, n))
+				}
+				d.comma(uint16(v))
+			}
+			continue
+		}
+		if !d.literal(w) {
+			return fail(fmt.Sprintf(`undefined word "%s"`, w))
+		}
+	}
+	if colon == ":" && !ended {
+		return fail("missing ;")
+	}
+	if colon == "doLIST" && len(d.control) > 0 {
+		return fail(fmt.Sprintf("%s without end", d.control[len(d.control)-1].kind))
+	}
+	header := uint16((len(name)/CELLL + 3) * CELLL)
+	if int(d.here()) > int(f._NP-header) {
+		return fail("no room in the dictionary")
+	}
+	copy(f.Memory[d.start:], d.code)
+	f.newWord(name, d.start, flags)
+	f.prims += uint16(len(d.code) / CELLL)
+	f.endWord()
+	return nil
+}
+
+// take the dictionary pointers, words may have been compiled since the
+// host added one
+func (f *Forth) beginWord() {
+	if f.booted {
+		f.readPointers(f.userAddr("CP"))
+	}
+}
+
+// give back the dictionary pointers after the host added a word, so COLD
+// and the text interpreter find it
+func (f *Forth) endWord() {
+	// the cold start CP, NP and LAST are the last cells of the user area
+	n, _ := f.Addr("ULAST-UZERO")
+	f.writePointers(n - 3*CELLL)
+	if f.booted {
+		f.writePointers(f.userAddr("CP"))
+		overt, _ := f.Addr("OVERT")
+		f.run(overt)
+	}
+}
+
+// set the host dictionary pointers from the CP, NP and LAST cells at a
+func (f *Forth) readPointers(a uint16) {
+	f.prims = (f.WordPtr(a) - CODEE + CELLL - 1) / CELLL
+	f._NP = f.WordPtr(a + CELLL)
+	f._LAST = f.WordPtr(a + 2*CELLL)
+}
+
+// store the host dictionary pointers in the CP, NP and LAST cells at a
+func (f *Forth) writePointers(a uint16) {
+	f.SetWordPtr(a, CODEE+CELLL*f.prims)
+	f.SetWordPtr(a+CELLL, f._NP)
+	f.SetWordPtr(a+2*CELLL, f._LAST)
+}
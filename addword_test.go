@@ -0,0 +1,123 @@
+package eforth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// run src after compiling defs from go and return the output
+func runAddWord(t *testing.T, src string, defs ...string) string {
+	o := new(bytes.Buffer)
+	f := New(strings.NewReader(src+" BYE\r"), o)
+	for _, d := range defs {
+		if err := f.AddWord(d); err != nil {
+			t.Fatal(d, "should compile but", err)
+		}
+	}
+	f.Main()
+	out := o.String()
+	if i := strings.Index(out, src+" BYE"); i >= 0 {
+		out = out[i+len(src)+4:]
+	}
+	return out
+}
+
+func TestAddWordCompiles(t *testing.T) {
+	tests := []struct {
+		def  string
+		src  string
+		good string
+	}{
+		{": a1 0< IF -1 ELSE 1 THEN ;", "-5 a1 . 5 a1 .", " -1 1"},
+		{": a2 DUP IF DUP IF 1 + THEN 10 + THEN ;", "1 a2 . 0 a2 .", " 12 0"},
+		{": a3 BEGIN DUP WHILE DUP . 1 - REPEAT DROP ;", "3 a3", " 3 2 1"},
+		{": a4 BEGIN 1 - DUP 0 = UNTIL ;", "5 a4 .", " 0"},
+		{": a5 FOR I . NEXT ;", "2 a5", " 2 1 0"},
+		{": a6 FOR AFT I . THEN NEXT ;", "2 a6", " 1 0"},
+		{": a7 4 1 DO I . LOOP ;", "a7", " 1 2 3"},
+		{": a8 CASE 1 OF 11 ENDOF 2 OF 22 ENDOF 0 SWAP ENDCASE ;", "2 a8 . 7 a8 .", " 22 0"},
+		{": a9 $FF -1 ;", "a9 . .", " -1 255"},
+		{": b1 12. ;", "b1 D.", " 12"},
+		{": b2 1.5E0 ;", "b2 F.", " 1.5"},
+		{`: b3 ." hi there" ;`, "b3", "hi there"},
+		{`: b4 S" abc" TYPE S\" d\"e" TYPE ;`, "b4", `abcd"e`},
+		{": b5 ( n ( nested ) -- n ) 1 + \\ to the end\n 2 * ;", "3 b5 .", " 8"},
+		{": b6 DUP IF 1 - RECURSE THEN ;", "4 b6 .", " 0"},
+		{": b7 AHEAD 1 . THEN 2 . ;", "b7", " 2"},
+		{": b8 [COMPILE] ( ;", "b8 comment) 3 .", " 3"},
+		{": b9 POSTPONE DUP ; IMMEDIATE", ": sq b9 * ; 3 sq .", " 9"},
+		{": c1 POSTPONE IF ; IMMEDIATE", ": nz c1 1 ELSE 0 THEN ; 5 nz . 0 nz .", " 1 0"},
+	}
+	for _, v := range tests {
+		if out := runAddWord(t, v.src, v.def); !strings.HasPrefix(out, v.good) {
+			t.Errorf("%q should print %q but printed %q", v.def, v.good, out)
+		}
+	}
+}
+
+func TestAddWordImmediate(t *testing.T) {
+	f := New(nil, nil)
+	if err := f.AddWord(": seven 7 ; IMMEDIATE"); err != nil {
+		t.Fatal(err)
+	}
+	if _, flags, _ := f.findName("seven"); flags&IMEDD == 0 {
+		t.Fatal("seven should be immediate")
+	}
+	if err := f.AddWord(": bad seven ;"); err == nil || !strings.Contains(err.Error(), `"seven"`) {
+		t.Fatal("compiling an immediate word should fail but gave", err)
+	}
+	if out := runAddWord(t, ": late [ 9 ] LITERAL ; late .", ": nine 9 ; IMMEDIATE"); !strings.HasPrefix(out, " 9") {
+		t.Fatal("the forth compiler should use it but printed", out)
+	}
+}
+
+func TestPostpone(t *testing.T) {
+	o, f := NewForth(": p1 POSTPONE DUP ; IMMEDIATE : sq p1 * ;\r: p2 POSTPONE IF ; IMMEDIATE : nz p2 1 ELSE 0 THEN ;\r3 sq . 5 nz . 0 nz .\rBYE\r")
+	f.Main()
+	if !strings.Contains(o.String(), " 9 1 0 ok") {
+		t.Fatal("POSTPONE should compile DUP and run IF but printed", o)
+	}
+}
+
+func TestAddWordBase(t *testing.T) {
+	f := New(nil, nil)
+	f.boot()
+	f.SetWordPtr(f.userAddr("BASE"), 16)
+	if err := f.AddWord(": h1 10 ;"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := f.Call("h1"); err != nil || len(r) != 1 || r[0] != 16 {
+		t.Fatal("10 should be compiled in hex as 16 but h1 returned", r, err)
+	}
+}
+
+func TestAddWordErrors(t *testing.T) {
+	tests := []struct {
+		def string
+		err string
+	}{
+		{": e1 nosuch ;", `e1 line 1: undefined word "nosuch"`},
+		{": e2 DUP\nDROP\nnosuch ;", `e2 line 3: undefined word "nosuch"`},
+		{": e3 1 2", "e3 line 1: missing ;"},
+		{": e4 THEN ;", "e4 line 1: THEN without IF"},
+		{": e5 IF ;", "e5 line 1: IF without end"},
+		{": e6 BEGIN REPEAT ;", "e6 line 1: REPEAT without IF"},
+		{": e7 NEXT ;", "e7 line 1: NEXT without FOR"},
+		{": e8 ( oops ;", "e8 line 1: missing )"},
+		{`: e9 ." no end ;`, `e9 line 1: missing " after ."`},
+		{": e10 ; DUP", `e10 line 1: "DUP" after ;`},
+		{"e11 DUP ;", `line 1: definition starts with "e11" instead of :`},
+		{":", "line 1: missing name"},
+	}
+	f := New(nil, nil)
+	for _, v := range tests {
+		err := f.AddWord(v.def)
+		if err == nil || !strings.HasSuffix(err.Error(), v.err) {
+			t.Errorf("%q should fail with %q but gave %v", v.def, v.err, err)
+		}
+		if _, e := f.Addr(strings.Fields(v.def + " x")[1]); e == nil && v.def[0] == ':' {
+			t.Errorf("%q should not be defined after the error", v.def)
+		}
+	}
+}
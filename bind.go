@@ -0,0 +1,227 @@
+package eforth
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// a go function made a word by Bind
+type binding struct {
+	fn      reflect.Value
+	comment string // stack comment shown by SEE
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+/*
+Add the go function fn as the primitive word name.  The stack effect comes
+from the signature of fn: the arguments are popped with the last one on top
+and the results pushed in order.
+
+	int, int8, int16, uint, uint8, uint16   a cell, n or u
+	int32, int64, uint32, uint64            a double, d or ud
+	bool                                    a flag, true is -1
+	string                                  an address and length, c-addr u
+	float32, float64                        a float on the float stack, r
+
+A last result of type error is not pushed, the error is thrown with its
+message like a panic in fn.  Strings pushed are in the transient string
+buffer, like those of S".
+
+	f.Bind("CLAMP", func(x, lo, hi int16) int16 { ... })
+
+gives CLAMP ( n n n -- n ), shown by SEE.
+*/
+func (f *Forth) Bind(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return errors.New(fmt.Sprintf("%s: %T is not a function", name, fn))
+	}
+	t := v.Type()
+	if t.IsVariadic() {
+		return errors.New(fmt.Sprintf("%s: variadic functions can not be bound", name))
+	}
+	var stack, fstack [2][]string
+	effect := func(side int, typ reflect.Type) error {
+		s, ok := stackName(typ)
+		switch {
+		case !ok:
+			return errors.New(fmt.Sprintf("%s: can not bind %s", name, typ))
+		case s == "r":
+			fstack[side] = append(fstack[side], s)
+		default:
+			stack[side] = append(stack[side], s)
+		}
+		return nil
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		if err := effect(0, t.In(i)); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < t.NumOut(); i++ {
+		if i == t.NumOut()-1 && t.Out(i) == errorType {
+			break
+		}
+		if err := effect(1, t.Out(i)); err != nil {
+			return err
+		}
+	}
+	comment := stackComment("", stack)
+	if len(fstack[0])+len(fstack[1]) > 0 {
+		comment += " " + stackComment("F: ", fstack)
+	}
+	f.own()
+	if f.bound == nil {
+		f.bound = make(map[string]binding)
+	}
+	b := binding{v, comment}
+	f.bound[name] = b
+	f.AddPrim(name, f.boundPrim(name, b), 0)
+	f.declare(name, comment)
+	return nil
+}
+
+// return ( prefix in -- out ) for the names in s
+func stackComment(prefix string, s [2][]string) string {
+	c := "( " + prefix
+	for _, w := range s[0] {
+		c += w + " "
+	}
+	c += "--"
+	for _, w := range s[1] {
+		c += " " + w
+	}
+	return c + " )"
+}
+
+// return the name of the stack item of a go type and whether Bind takes it
+func stackName(t reflect.Type) (string, bool) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16:
+		return "n", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return "u", true
+	case reflect.Int32, reflect.Int64:
+		return "d", true
+	case reflect.Uint32, reflect.Uint64:
+		return "ud", true
+	case reflect.Bool:
+		return "flag", true
+	case reflect.String:
+		return "c-addr u", true
+	case reflect.Float32, reflect.Float64:
+		return "r", true
+	}
+	return "", false
+}
+
+// return the primitive running the function bound to name
+func (f *Forth) boundPrim(name string, b binding) fn {
+	t := b.fn.Type()
+	return func() {
+		floats := 0
+		for i := 0; i < t.NumIn(); i++ {
+			if k := t.In(i).Kind(); k == reflect.Float32 || k == reflect.Float64 {
+				floats++
+			}
+		}
+		if f.FDepth() < floats {
+			f.throwMessage("float stack underflow")
+			return
+		}
+		args := make([]reflect.Value, t.NumIn())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = f.popValue(t.In(i))
+		}
+		var out []reflect.Value
+		err := func() (err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					err = errors.New(fmt.Sprint(p))
+				}
+			}()
+			out = b.fn.Call(args)
+			return nil
+		}()
+		if n := len(out); err == nil && n > 0 && t.Out(n-1) == errorType {
+			if e := out[n-1]; !e.IsNil() {
+				err = e.Interface().(error)
+			}
+			out = out[:n-1]
+		}
+		if err != nil {
+			f.throwMessage(name + ": " + err.Error())
+			return
+		}
+		for _, v := range out {
+			if err := f.pushValue(v); err != nil {
+				f.throwMessage(name + ": " + err.Error())
+				return
+			}
+		}
+		f.Next()
+	}
+}
+
+// pop an argument of type t
+func (f *Forth) popValue(t reflect.Type) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16:
+		v.SetInt(int64(asint16(f.Pop())))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16:
+		v.SetUint(uint64(f.Pop()))
+	case reflect.Int32, reflect.Int64:
+		v.SetInt(int64(f.popDouble()))
+	case reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(uint32(f.popDouble())))
+	case reflect.Bool:
+		v.SetBool(f.Pop() != 0)
+	case reflect.String:
+		v.SetString(f.PopString())
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(f.FPop())
+	}
+	return v
+}
+
+// push a result, the value is cut to the cells it takes
+func (f *Forth) pushValue(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16:
+		f.Push(uint16(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16:
+		f.Push(uint16(v.Uint()))
+	case reflect.Int32, reflect.Int64:
+		f.pushDouble(int32(v.Int()))
+	case reflect.Uint32, reflect.Uint64:
+		f.pushDouble(int32(uint32(v.Uint())))
+	case reflect.Bool:
+		if v.Bool() {
+			f.Push(asuint16(-1))
+		} else {
+			f.Push(0)
+		}
+	case reflect.String:
+		return f.PushString(v.String())
+	case reflect.Float32, reflect.Float64:
+		return f.FPush(v.Float())
+	}
+	return nil
+}
+
+/*
+Return the stack comment of a word added with Bind, or "" for other words.
+*/
+func (f *Forth) StackComment(name string) string {
+	return f.bound[name].comment
+}
+
+// make the words bound in f run the same functions in g
+func (f *Forth) rebind(g *Forth) {
+	for name, b := range f.bound {
+		g.prim2func[name] = g.boundPrim(name, b)
+	}
+}
@@ -0,0 +1,129 @@
+package eforth
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// bind words to a new Forth and return what src prints
+func runBound(t *testing.T, src string, bind func(f *Forth) error) string {
+	o := new(bytes.Buffer)
+	f := New(strings.NewReader(src+"\rBYE\r"), o)
+	if err := bind(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	out := o.String()
+	if i := strings.Index(out, src); i >= 0 {
+		out = out[i+len(src):]
+	}
+	if i := strings.LastIndex(out, "BYE"); i >= 0 {
+		out = out[:i]
+	}
+	return strings.TrimSpace(out)
+}
+
+func TestBind(t *testing.T) {
+	bind := func(f *Forth) error {
+		for _, v := range []struct {
+			name string
+			fn   interface{}
+		}{
+			{"CLAMP", func(x, lo, hi int16) int16 {
+				if x < lo {
+					return lo
+				}
+				if x > hi {
+					return hi
+				}
+				return x
+			}},
+			{"DIVMOD", func(a, b int) (int, int) { return a / b, a % b }},
+			{"EVEN?", func(u uint16) bool { return u%2 == 0 }},
+			{"UPPER", strings.ToUpper},
+			{"BIG", func(d int32) int64 { return int64(d) * 1000 }},
+			{"HALF", func(r float64) float64 { return r / 2 }},
+		} {
+			if err := f.Bind(v.name, v.fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, v := range []struct{ src, out string }{
+		{"15 0 10 CLAMP . -5 0 10 CLAMP . 7 0 10 CLAMP .", "10 0 7 ok"},
+		{"17 5 DIVMOD . .", "2 3 ok"},
+		{"4 EVEN? . 5 EVEN? .", "-1 0 ok"},
+		{`S" abc" UPPER TYPE`, "ABC ok"},
+		{"70. BIG D.", "70000 ok"},
+		{"3E0 HALF F.", "1.5 ok"},
+	} {
+		if out := runBound(t, v.src, bind); out != v.out {
+			t.Errorf("%s should print %q but printed %q", v.src, v.out, out)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	bind := func(f *Forth) error {
+		return f.Bind("FAIL", func(n int) (int, error) {
+			if n < 0 {
+				return 0, errors.New("negative")
+			}
+			return n, nil
+		})
+	}
+	if err := New(nil, nil).Bind("MAP", func(map[int]int) {}); err == nil {
+		t.Error("should not bind a map argument")
+	}
+	if err := New(nil, nil).Bind("NUM", 5); err == nil {
+		t.Error("should not bind a number")
+	}
+	if err := New(nil, nil).Bind("SUM", func(n ...int) {}); err == nil {
+		t.Error("should not bind a variadic function")
+	}
+	if out := runBound(t, "1 FAIL . -1 FAIL", bind); !strings.Contains(out, "1 ") || !strings.Contains(out, "FAIL: negative ?") {
+		t.Error("the error should be thrown but printed", out)
+	}
+	if out := runBound(t, "5 INDEX", func(g *Forth) error {
+		return g.Bind("INDEX", func(n int) int { return []int{1}[n] })
+	}); !strings.Contains(out, "INDEX: runtime error: index out of range") {
+		t.Error("the panic should be thrown but printed", out)
+	}
+}
+
+func TestBindSee(t *testing.T) {
+	out := runBound(t, "SEE CLAMP SEE FSCALE SEE DUP", func(f *Forth) error {
+		if err := f.Bind("CLAMP", func(x, lo, hi int16) int16 { return x }); err != nil {
+			return err
+		}
+		return f.Bind("FSCALE", func(s string, r float64, n int) (float64, bool, error) { return r, true, nil })
+	})
+	for _, want := range []string{
+		"CODE CLAMP ( n n n -- n )",
+		"CODE FSCALE ( c-addr u n -- flag ) ( F: r -- r )",
+		"CODE DUP ok",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("SEE should show %q but printed %q", want, out)
+		}
+	}
+}
+
+// a Clone runs the bound functions on its own stacks
+func TestBindClone(t *testing.T) {
+	f := New(nil, nil)
+	f.Bind("TWICE", func(n int) int { return 2 * n })
+	g := f.Clone()
+	o := new(bytes.Buffer)
+	g.Input, g.Output = strings.NewReader("21 TWICE .\rBYE\r"), o
+	g.Main()
+	if !strings.Contains(o.String(), " 42 ok") {
+		t.Fatal("the clone should run TWICE but printed", o.String())
+	}
+	if g.StackComment("TWICE") != "( n -- n )" || f.StackComment("DUP") != "" {
+		t.Fatal("only bound words should have a stack comment")
+	}
+}
@@ -0,0 +1,276 @@
+package eforth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	BLOCKK = 1024 // size of a block
+	LINESS = 64   // characters in a line of a screen
+)
+
+/*
+A block buffer in Memory and the block it holds.
+*/
+type blockBuffer struct {
+	addr    uint16
+	blk     int // -1 if unassigned
+	updated bool
+}
+
+func (f *Forth) addBlocks() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"BLOCK", "BLOCK", f._Block, 0},
+		{"BUFFER", "BUFFE", f._Buffer, 0},
+		{"UPDATE", "UPDAT", f._Update, 0},
+		{"SAVE-BUFFERS", "SAVEB", f._SaveBuffers, 0},
+		{"EMPTY-BUFFERS", "EMPTB", f._EmptyBuffers, 0},
+		{"(block)", "OPENB", f._OpenBlock, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Blocks
+
+;   SCR		( -- a )
+;		Hold the number of the screen last listed.
+
+		$COLON	3,'SCR',SCR
+		DW	DOVAR
+		DW	0
+
+;   FLUSH	( -- )
+;		Write all updated buffers and unassign all buffers.
+
+		$COLON	5,'FLUSH',FLUSH
+		DW	SAVEB,EMPTB,EXIT
+
+;   LOAD	( u -- )
+;		Interpret block u.
+
+		$COLON	4,'LOAD',LOAD
+		DW	OPENB,PINCL,EXIT
+
+;   THRU	( u1 u2 -- )
+;		Interpret blocks u1 through u2, none if u2 is below u1.
+
+		$COLON	4,'THRU',THRU
+		DW	DDUP,SWAP,ULESS		;?u2 below u1
+		DW	QBRAN,THRU0
+		DW	DDROP,EXIT
+THRU0:		DW	OVER,SUBB,SWAP,TOR,TOR	;keep block and count off the data stack
+THRU1:		DW	RFROM,RFROM,DUPP,DOLIT,1,PLUS
+		DW	TOR,SWAP,TOR,LOAD
+		DW	DONXT,THRU1
+		DW	RFROM,DROP,EXIT
+
+;   LIST	( u -- )
+;		Display block u as a screen of 16 numbered lines.
+
+		$COLON	4,'LIST',LIST
+		DW	DUPP,SCR,STORE,CR
+		D$	DOTQP,'Scr #'
+		DW	DUPP,DOT,BLOCK
+		DW	DOLIT,15,TOR
+LIST1:		DW	CR,DOLIT,15,RAT,SUBB,DOLIT,2,UDOTR
+		DW	SPACE,DUPP,DOLIT,64,UTYPE
+		DW	DOLIT,64,PLUS
+		DW	DONXT,LIST1
+		DW	DROP,CR,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+/*
+Use the file at path, created if needed, for the blocks and keep up to n of
+them in buffers of BLOCKK bytes taken from the code dictionary.  Block u is
+stored at offset u*BLOCKK.
+
+Updated buffers of a previous block file are saved first.  The buffers are
+reused and only those missing are taken from the dictionary.
+*/
+func (f *Forth) UseBlocks(path string, n int) error {
+	if n < 1 {
+		return errors.New("need at least one block buffer")
+	}
+	if err := f.SaveBuffers(); err != nil {
+		return err
+	}
+	fd, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	for len(f.buffers) < n {
+		a, err := f.allot(BLOCKK)
+		if err != nil {
+			fd.Close()
+			return err
+		}
+		f.buffers = append(f.buffers, blockBuffer{addr: a, blk: -1})
+	}
+	f.emptyBuffers()
+	if f.blockFile != nil {
+		f.blockFile.Close()
+	}
+	f.blockFile = fd
+	return nil
+}
+
+/*
+Write the updated block buffers to the block file.
+*/
+func (f *Forth) SaveBuffers() error {
+	for i := range f.buffers {
+		b := &f.buffers[i]
+		if b.blk < 0 || !b.updated {
+			continue
+		}
+		if _, err := f.blockFile.WriteAt(f.Memory[b.addr:b.addr+BLOCKK], int64(b.blk)*BLOCKK); err != nil {
+			return err
+		}
+		b.updated = false
+	}
+	return nil
+}
+
+func (f *Forth) emptyBuffers() {
+	for i := range f.buffers {
+		f.buffers[i].blk = -1
+		f.buffers[i].updated = false
+	}
+	f.lastbuf = 0
+}
+
+// find the buffer assigned to block u or assign one, saving it if updated
+func (f *Forth) assignBuffer(u int) (b *blockBuffer, fresh bool, err error) {
+	if f.blockFile == nil {
+		return nil, false, errors.New("no block file")
+	}
+	victim := -1
+	for i := range f.buffers {
+		if f.buffers[i].blk == u {
+			f.lastbuf = i
+			return &f.buffers[i], false, nil
+		}
+		if victim == -1 && f.buffers[i].blk < 0 {
+			victim = i
+		}
+	}
+	if victim == -1 {
+		victim = (f.lastbuf + 1) % len(f.buffers)
+	}
+	b = &f.buffers[victim]
+	if b.blk >= 0 && b.updated {
+		if _, err := f.blockFile.WriteAt(f.Memory[b.addr:b.addr+BLOCKK], int64(b.blk)*BLOCKK); err != nil {
+			return nil, false, err
+		}
+	}
+	b.blk = u
+	b.updated = false
+	f.lastbuf = victim
+	return b, true, nil
+}
+
+// read block u into its buffer, blanks past the end of the file
+func (f *Forth) readBlock(u int) (uint16, error) {
+	b, fresh, err := f.assignBuffer(u)
+	if err != nil {
+		return 0, err
+	}
+	if fresh {
+		buf := f.Memory[b.addr : b.addr+BLOCKK]
+		n, err := f.blockFile.ReadAt(buf, int64(u)*BLOCKK)
+		if err != nil && err != io.EOF {
+			b.blk = -1
+			return 0, err
+		}
+		for i := n; i < BLOCKK; i++ {
+			buf[i] = ' '
+		}
+	}
+	return b.addr, nil
+}
+
+// BLOCK ( u -- a ) return the buffer holding block u, read from the file if needed
+func (f *Forth) _Block() {
+	u := f.Pop()
+	a, err := f.readBlock(int(u))
+	if err != nil {
+		f.throwMessage(err.Error())
+		return
+	}
+	f.Push(a)
+	f.Next()
+}
+
+// BUFFER ( u -- a ) assign a buffer to block u without reading it
+func (f *Forth) _Buffer() {
+	u := f.Pop()
+	b, _, err := f.assignBuffer(int(u))
+	if err != nil {
+		f.throwMessage(err.Error())
+		return
+	}
+	f.Push(b.addr)
+	f.Next()
+}
+
+// UPDATE ( -- ) mark the buffer used last as modified
+func (f *Forth) _Update() {
+	if len(f.buffers) > 0 && f.buffers[f.lastbuf].blk >= 0 {
+		f.buffers[f.lastbuf].updated = true
+	}
+	f.Next()
+}
+
+// SAVE-BUFFERS ( -- ) write all updated buffers to the block file
+func (f *Forth) _SaveBuffers() {
+	if err := f.SaveBuffers(); err != nil {
+		f.throwMessage(err.Error())
+		return
+	}
+	f.Next()
+}
+
+// EMPTY-BUFFERS ( -- ) unassign all buffers without saving them
+func (f *Forth) _EmptyBuffers() {
+	f.emptyBuffers()
+	f.Next()
+}
+
+// (block) ( u -- ) make the lines of block u the input source
+func (f *Forth) _OpenBlock() {
+	u := f.Pop()
+	a, err := f.readBlock(int(u))
+	if err != nil {
+		f.throwMessage(err.Error())
+		return
+	}
+	text := string(f.Memory[a : a+BLOCKK])
+	line := 0
+	f.pushSource(&source{
+		name: fmt.Sprintf("block %d", u),
+		next: func() (string, error) {
+			if line == BLOCKK/LINESS {
+				return "", io.EOF
+			}
+			line += 1
+			return text[(line-1)*LINESS : line*LINESS], nil
+		},
+	})
+	f.Next()
+}
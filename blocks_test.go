@@ -0,0 +1,132 @@
+package eforth
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// a block file with screen 1 loading screens 2 and 3
+func blockFile(t *testing.T) string {
+	screens := map[int][]string{
+		1: {"\\ load screen", "2 3 THRU"},
+		2: {": sq DUP * ;"},
+		3: {"7 sq"},
+	}
+	buf := bytes.Repeat([]byte(" "), 4*BLOCKK)
+	for n, lines := range screens {
+		for i, l := range lines {
+			copy(buf[n*BLOCKK+i*LINESS:], l)
+		}
+	}
+	p := filepath.Join(t.TempDir(), "blocks.fb")
+	if err := os.WriteFile(p, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadBlocks(t *testing.T) {
+	p := blockFile(t)
+	i := strings.NewReader("1 LOAD BYE\r")
+	f := New(i, new(bytes.Buffer))
+	if err := f.UseBlocks(p, 2); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	if x := f.Pop(); x != 49 {
+		t.Fatal("1 LOAD should have left 49 but left", x)
+	}
+}
+
+func TestUpdateBlocks(t *testing.T) {
+	p := blockFile(t)
+	// more blocks than buffers so block 5 gets written when its buffer is reused
+	i := strings.NewReader("5 BUFFER 64 BL FILL 5 BLOCK CHAR x SWAP C! UPDATE\r1 BLOCK DROP 2 BLOCK DROP 5 BLOCK C@ BYE\r")
+	f := New(i, new(bytes.Buffer))
+	if err := f.UseBlocks(p, 2); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	if x := f.Pop(); x != 'x' {
+		t.Fatal("block 5 should start with x but has", x)
+	}
+	buf, _ := os.ReadFile(p)
+	if len(buf) < 6*BLOCKK || buf[5*BLOCKK] != 'x' {
+		t.Fatal("block 5 was not written to the file")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	p := blockFile(t)
+	i := strings.NewReader("CHAR y 3 BLOCK C! UPDATE FLUSH CHAR z 3 BLOCK C! EMPTY-BUFFERS 3 BLOCK C@ BYE\r")
+	f := New(i, new(bytes.Buffer))
+	if err := f.UseBlocks(p, 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	if x := f.Pop(); x != 'y' {
+		t.Fatal("block 3 should start with y after FLUSH but has", x)
+	}
+}
+
+func TestList(t *testing.T) {
+	p := blockFile(t)
+	o := new(bytes.Buffer)
+	f := New(strings.NewReader("2 LIST SCR @ BYE\r"), o)
+	if err := f.UseBlocks(p, 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	t.Log(o.String())
+	if !strings.Contains(o.String(), "Scr # 2") || !strings.Contains(o.String(), " 0 : sq DUP * ;") || !strings.Contains(o.String(), "15 ") {
+		t.Fatal("LIST should display screen 2")
+	}
+	if x := f.Pop(); x != 2 {
+		t.Fatal("SCR should be 2 but is", x)
+	}
+}
+
+func TestLoadBlockError(t *testing.T) {
+	p := blockFile(t)
+	f := New(nil, new(bytes.Buffer))
+	if err := f.UseBlocks(p, 2); err != nil {
+		t.Fatal(err)
+	}
+	a := writeSource(t, t.TempDir(), "a.fth", "0 LOAD\n")
+	err := f.LoadFile(a)
+	if err != nil {
+		t.Fatal("blank block 0 should load but got", err)
+	}
+	b := writeSource(t, t.TempDir(), "b.fth", "3 LOAD sq\n")
+	err = f.LoadFile(b)
+	if err == nil || err.Error() != "block 3:1: sq ?" {
+		t.Fatal("sq is not defined in block 3 but got", err)
+	}
+}
+
+func TestThruNone(t *testing.T) {
+	p := blockFile(t)
+	f := New(strings.NewReader("1 3 2 THRU BYE\r"), new(bytes.Buffer))
+	if err := f.UseBlocks(p, 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	if x := f.Pop(); x != 1 || f.SP != SPP {
+		t.Fatal("3 2 THRU should load nothing but left", x)
+	}
+}
+
+func TestUseBlocksAgain(t *testing.T) {
+	p := blockFile(t)
+	f := New(nil, new(bytes.Buffer))
+	f.UseBlocks(p, 2)
+	cp, _, _ := f.pointers()
+	f.UseBlocks(p, 3)
+	f.UseBlocks(p, 1)
+	if c, _, _ := f.pointers(); c != cp+BLOCKK || len(f.buffers) != 3 {
+		t.Fatalf("3 buffers should take one more block but CP went from %X to %X", cp, c)
+	}
+}
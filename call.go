@@ -0,0 +1,83 @@
+package eforth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// returned by Call when the word runs more steps than StepBudget allows
+var ErrBudget = errors.New("step budget exhausted")
+
+/*
+Run the word name with args pushed on the data stack, the last on top, and
+return the cells it leaves there, deepest first.  The stacks are as they
+were before when Call returns, also after an error.
+
+Call can be used from a go primitive while Main runs, the word runs nested
+in it like EXECUTE.  An error the word throws is returned like LoadFile
+returns it, BYE returns ErrBye and running more than StepBudget steps
+returns ErrBudget.
+*/
+func (f *Forth) Call(name string, args ...int16) ([]int16, error) {
+	f.boot()
+	ca, ok := f.lookup(name)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf(`undefined word "%s"`, name))
+	}
+	ip, wp, awp, rp, sp, fp, lp := f.IP, f.WP, f.aWP, f.RP, f.SP, f.FP, f.LP
+	handler := f.userAddr("HANDLER")
+	frame := f.WordPtr(handler) // CATCH frame left behind if the word stops
+	restore := func() {
+		f.IP, f.WP, f.aWP, f.RP, f.SP, f.FP, f.LP = ip, wp, awp, rp, sp, fp, lp
+		f.SetWordPtr(handler, frame)
+	}
+	limit := f.limit
+	if f.StepBudget > 0 && (limit == 0 || f.steps+f.StepBudget < limit) {
+		f.limit = f.steps + f.StepBudget
+	}
+	defer func() { f.limit = limit }()
+
+	for _, a := range args {
+		f.Push(uint16(a))
+	}
+	e, ok := f.catch(ca)
+	switch {
+	case !ok && f.limit != 0 && f.steps >= f.limit:
+		restore()
+		return nil, ErrBudget
+	case !ok:
+		restore()
+		return nil, ErrBye
+	case e != 0:
+		restore()
+		return nil, errors.New(fmt.Sprintf("%s ?", f.countedString(e)))
+	case f.SP > sp:
+		restore()
+		return nil, errors.New(fmt.Sprintf("%s: stack underflow", name))
+	}
+	res := make([]int16, (sp-f.SP)/CELLL)
+	for i := range res {
+		res[len(res)-1-i] = asint16(f.Pop())
+	}
+	restore()
+	return res, nil
+}
+
+/*
+Return the code address of the word name, the latest definition first.
+*/
+func (f *Forth) lookup(name string) (uint16, bool) {
+	na := f._LAST
+	if f.booted {
+		na = f.WordPtr(f.userAddr("LAST"))
+	}
+	for na != 0 && int(na) < EM {
+		n := int(f.Memory[na] & 0x1F)
+		if int(na)+1+n <= EM && string(f.Memory[na+1:int(na)+1+n]) == name {
+			return f.WordPtr(na - 2*CELLL), true
+		}
+		na = f.WordPtr(na - CELLL)
+	}
+	ca, err := f.Addr(name)
+	return ca, err == nil
+}
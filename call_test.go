@@ -0,0 +1,82 @@
+package eforth
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	f := New(nil, new(bytes.Buffer))
+	if err := f.Include("", strings.NewReader(": sq DUP * ; : divmod /MOD SWAP ;")); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		word string
+		args []int16
+		res  []int16
+	}{
+		{"sq", []int16{-7}, []int16{49}},
+		{"divmod", []int16{17, 5}, []int16{3, 2}},
+		{"DROP", []int16{1}, []int16{}},
+		{"DEPTH", nil, []int16{0}},
+		{"ROT", []int16{1, 2, 3}, []int16{2, 3, 1}},
+	} {
+		res, err := f.Call(v.word, v.args...)
+		if err != nil || !reflect.DeepEqual(res, v.res) {
+			t.Errorf("%s %v should return %v but returned %v %v", v.word, v.args, v.res, res, err)
+		}
+	}
+	if f.SP != SPP {
+		t.Error("the data stack should be empty after Call")
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	f := New(nil, new(bytes.Buffer))
+	src := ": boom 1 2 S\" boom\" ABORT\" broken\" ; : loop BEGIN AGAIN ; : bye BYE ;"
+	if err := f.Include("", strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Call("nosuch"); err == nil {
+		t.Error("an undefined word should be an error")
+	}
+	if _, err := f.Call("boom"); err == nil || err.Error() != "broken ?" {
+		t.Error("boom should throw broken but returned", err)
+	}
+	if _, err := f.Call("DROP"); err == nil || !strings.Contains(err.Error(), "underflow") {
+		t.Error("DROP on an empty stack should underflow but returned", err)
+	}
+	if _, err := f.Call("bye"); err != ErrBye {
+		t.Error("BYE should return ErrBye but returned", err)
+	}
+	f.StepBudget = 1000
+	if _, err := f.Call("loop"); err != ErrBudget {
+		t.Error("an endless loop should exhaust the budget but returned", err)
+	}
+	if res, err := f.Call("+", 1, 2); err != nil || res[0] != 3 {
+		t.Error("Call should work after errors but returned", res, err)
+	}
+	if f.SP != SPP || f.RP != RPP {
+		t.Error("the stacks should be as before after errors")
+	}
+}
+
+// Call from a primitive that Main runs
+func TestCallNested(t *testing.T) {
+	o := new(bytes.Buffer)
+	f := New(strings.NewReader(": sq DUP * ;\r3 hyp .\rBYE\r"), o)
+	f.Bind("hyp", func(n int) (int, error) {
+		a, err := f.Call("sq", int16(n))
+		if err != nil {
+			return 0, err
+		}
+		b, err := f.Call("sq", 4)
+		return int(a[0] + b[0]), err
+	})
+	f.Main()
+	if !strings.Contains(o.String(), " 25 ok") {
+		t.Fatal("hyp should call sq from go but printed", o.String())
+	}
+}
@@ -0,0 +1,120 @@
+package eforth
+
+import (
+	"sync"
+)
+
+// the instance New clones, see baseImage
+var base struct {
+	once sync.Once
+	f    *Forth
+}
+
+/*
+Return the instance New clones, it is built the first time it is needed and
+never changed after, so it can be cloned from any goroutine.
+*/
+func baseImage() *Forth {
+	base.once.Do(func() {
+		base.f = build()
+		base.f.shared = true
+	})
+	return base.f
+}
+
+/*
+Return a copy of f that runs on its own.  The memory, registers and
+dictionaries are copied, without assembling the listings again, and the
+primitives are bound to the copy.  The maps of names are shared until f or
+the copy adds a word, so cloning is cheap.
+
+Primitives added with AddPrim are copied as they are, so they still call
+the functions given, which may refer to f, those added with Bind run on the
+copy.  The Clock is shared, a VirtualClock advances for both.  The copy
+has no block file open and no input being read, set Input and Output
+before calling Main.  Clone must not be called while Main runs f.
+*/
+func (f *Forth) Clone() *Forth {
+	// run the add functions with the primitives only to bind them to g
+	g := &Forth{
+		prim2addr:  make(map[string]uint16),
+		addr2word:  make(map[uint16]string),
+		prim2func:  make(map[string]fn),
+		pcode2word: make(map[uint16]string),
+		asm2forth:  make(map[string]string),
+		binding:    true,
+	}
+	g.addWords()
+	prim2func, macros := g.prim2func, g.macros
+
+	*g = *f
+	g.prim2func, g.macros = prim2func, macros
+	for w := range f.hostPrims {
+		g.prim2func[w] = f.prim2func[w]
+	}
+	f.rebind(g)
+	if !f.shared {
+		f.shared = true // the base is read by clones in other goroutines
+	}
+	g.shared = true
+	g.rxchan = nil
+	g.rxquit = nil
+	g.pending, g.irq, g.stopped = 0, 0, 0
+	g.record, g.replay = nil, nil
+	g.cover, g.arms = nil, nil
+	g.actual, g.TestFailed = nil, nil
+	g.timers = append([]timer(nil), f.timers...)
+	g.sources = nil
+	g.blockFile = nil
+	g.buffers = append([]blockBuffer(nil), f.buffers...)
+	for i := range g.buffers {
+		g.buffers[i].blk = -1
+		g.buffers[i].updated = false
+	}
+	g.locals = append([]string(nil), f.locals...)
+	return g
+}
+
+/*
+Copy the maps shared with a clone before changing them.
+*/
+func (f *Forth) own() {
+	if !f.shared {
+		return
+	}
+	prim2addr := make(map[string]uint16, len(f.prim2addr))
+	for k, v := range f.prim2addr {
+		prim2addr[k] = v
+	}
+	addr2word := make(map[uint16]string, len(f.addr2word))
+	for k, v := range f.addr2word {
+		addr2word[k] = v
+	}
+	pcode2word := make(map[uint16]string, len(f.pcode2word))
+	for k, v := range f.pcode2word {
+		pcode2word[k] = v
+	}
+	asm2forth := make(map[string]string, len(f.asm2forth))
+	for k, v := range f.asm2forth {
+		asm2forth[k] = v
+	}
+	hostPrims := make(map[string]bool, len(f.hostPrims))
+	for k, v := range f.hostPrims {
+		hostPrims[k] = v
+	}
+	effects := make(map[uint16]string, len(f.effects))
+	for k, v := range f.effects {
+		effects[k] = v
+	}
+	var bound map[string]binding
+	if f.bound != nil {
+		bound = make(map[string]binding, len(f.bound))
+		for k, v := range f.bound {
+			bound[k] = v
+		}
+	}
+	f.prim2addr, f.addr2word, f.pcode2word = prim2addr, addr2word, pcode2word
+	f.asm2forth, f.hostPrims, f.bound = asm2forth, hostPrims, bound
+	f.effects = effects
+	f.shared = false
+}
@@ -0,0 +1,87 @@
+package eforth
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCloneMatchesBuild(t *testing.T) {
+	b := build()
+	n := New(nil, nil)
+	if b.Memory != n.Memory {
+		t.Error("the memory of New should be the memory of a built instance")
+	}
+	for _, v := range []struct {
+		name string
+		a, b interface{}
+	}{
+		{"prim2addr", b.prim2addr, n.prim2addr},
+		{"addr2word", b.addr2word, n.addr2word},
+		{"pcode2word", b.pcode2word, n.pcode2word},
+		{"asm2forth", b.asm2forth, n.asm2forth},
+	} {
+		if !reflect.DeepEqual(v.a, v.b) {
+			t.Error(v.name, "of New should be the same as a built instance")
+		}
+	}
+	if len(b.prim2func) != len(n.prim2func) {
+		t.Error("New has", len(n.prim2func), "primitives instead of", len(b.prim2func))
+	}
+	if b.prims != n.prims || b._NP != n._NP || b._LAST != n._LAST || b.fsp0 != n.fsp0 {
+		t.Error("the dictionary pointers of New should be those of a built instance")
+	}
+}
+
+func TestCloneIndependent(t *testing.T) {
+	f := New(nil, nil)
+	if err := f.AddWord(": one 1 ;"); err != nil {
+		t.Fatal(err)
+	}
+	g := f.Clone()
+	if err := g.AddWord(": two 2 ;"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Addr("two"); err == nil {
+		t.Fatal("a word added to the clone should not be in the original")
+	}
+	run := func(f *Forth, src string) string {
+		o := new(bytes.Buffer)
+		f.Input, f.Output = strings.NewReader(src+" BYE\r"), o
+		f.Main()
+		return o.String()
+	}
+	if out := run(g, "one two + ."); !strings.Contains(out, "BYE 3") {
+		t.Fatal("the clone should know both words but printed", out)
+	}
+	if out := run(f, "one . 5 VALUE two"); !strings.Contains(out, "BYE 1") {
+		t.Fatal("the original should run on its own but printed", out)
+	}
+	if f.Memory == g.Memory {
+		t.Fatal("the clone and the original should not share memory")
+	}
+}
+
+// cloning the base has to be cheaper than building, or New is no use
+func TestNewCheaperThanBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("times the benchmarks")
+	}
+	n, b := testing.Benchmark(BenchmarkNew), testing.Benchmark(BenchmarkBuild)
+	if n.NsPerOp() >= b.NsPerOp() {
+		t.Fatalf("New takes %dns, not less than build's %dns", n.NsPerOp(), b.NsPerOp())
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		New(nil, nil)
+	}
+}
+
+func BenchmarkBuild(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		build()
+	}
+}
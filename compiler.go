@@ -0,0 +1,72 @@
+package eforth
+
+import (
+	"fmt"
+)
+
+/*
+Redefine the compiler after the number word sets so literals of every kind
+NUMBER? converts are compiled with the matching LITERAL, and add POSTPONE,
+which AddWord compiles the same way.
+*/
+func (f *Forth) addCompiler() {
+	err := f.WordFromASM(`
+
+;; Compiling literals
+
+;   $COMPILE	( a -- )
+;		Compile next word to code dictionary as a token or literal.
+
+		$COLON	8,'$COMPILE',SCOMP
+		DW	LOCQ			;?local
+		DW	QBRAN,SCOM0
+		DW	EXIT			;its a local, compiled
+SCOM0:		DW	NAMEQ,QDUP		;?defined
+		DW	QBRAN,SCOM2
+		DW	AT,DOLIT,IMEDD,ANDD	;?immediate
+		DW	QBRAN,SCOM1
+		DW	EXECU,EXIT		;its immediate, execute
+SCOM1:		DW	COMMA,EXIT		;its not immediate, compile
+SCOM2:		DW	DOLIT,-1,DPL,STORE
+		DW	FDEPT,TOR
+		DW	TNUMB,ATEXE		;try to convert to number
+		DW	QBRAN,SCOM5
+		DW	FDEPT,RFROM,XORR	;?float
+		DW	QBRAN,SCOM3
+		DW	FLITE,EXIT		;compile number as float
+SCOM3:		DW	DPL,AT,ZLESS		;?single
+		DW	QBRAN,SCOM4
+		DW	LITER,EXIT		;compile number as integer
+SCOM4:		DW	DLITE,EXIT		;compile number as double
+SCOM5:		DW	RFROM,DROP,THROW	;error
+
+;   ]		( -- )
+;		Start compiling the words in the input stream.
+
+		$COLON	1,']',RBRAC
+		DW	DOLIT,SCOMP,TEVAL,STORE,EXIT
+
+;   :		( -- ; <string> )
+;		Start a new colon definition using next word as its name.
+
+		$COLON	1,':',COLON
+		DW	NOLOC,TOKEN,SNAME,DOLIT,DOLST
+		DW	CALLC,RBRAC,EXIT
+
+;   POSTPONE	( -- ; <string> )
+;		Compile the next word so it is compiled when the word being defined runs,
+;		or runs then if it is immediate.
+
+		$COLON	IMEDD+8,'POSTPONE',POSTP
+		DW	TOKEN,NAMEQ,QDUP	;?defined
+		DW	QBRAN,POST2
+		DW	AT,DOLIT,IMEDD,ANDD	;?immediate
+		DW	QBRAN,POST1
+		DW	COMMA,EXIT		;its immediate, compile it
+POST1:		DW	COMPI,COMPI,COMMA,EXIT	;its not, compile COMPILE and it
+POST2:		DW	THROW			;error
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
@@ -0,0 +1,40 @@
+package eforth
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Build and run many instances at once, run with go test -race to check
+// that they share no state.
+func TestParallelInstances(t *testing.T) {
+	const n = 32
+	var wg sync.WaitGroup
+	errs := make(chan string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			src := fmt.Sprintf(`: sq DUP * ; id%d sq . 1.5E0 F. S" x" TYPE`, i)
+			o := new(bytes.Buffer)
+			f := New(strings.NewReader(src+" BYE\r"), o)
+			if err := f.AddWord(fmt.Sprintf(": id%d %d ;", i, i)); err != nil {
+				errs <- err.Error()
+				return
+			}
+			f.Main()
+			good := fmt.Sprintf(" %d 1.5x", i*i)
+			if !strings.Contains(o.String(), good) {
+				errs <- fmt.Sprintf("instance %d should print %q but printed %q", i, good, o.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Error(e)
+	}
+}
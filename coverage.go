@@ -0,0 +1,215 @@
+package eforth
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+)
+
+// branches that go one way or the other, their arms are covered apart
+var conditional = map[string]bool{
+	"?branch": true, "next": true, "(?do)": true, "(loop)": true, "(+loop)": true,
+}
+
+// the way a conditional branch went
+const (
+	armJumped = 1 << iota
+	armFell
+)
+
+/*
+Start counting how often every cell of the colon definitions runs and which
+way their branches go, for Coverage.  Counts from before are forgotten.
+*/
+func (f *Forth) StartCoverage() {
+	f.cover = make([]uint32, EM/CELLL)
+	f.arms = make([]uint8, EM/CELLL)
+}
+
+// note which way the conditional branch in the cell at a went, by the cell
+// it ran next
+func (f *Forth) coverBranch(a uint16) {
+	if f.aWP == a+2*CELLL {
+		f.arms[a/CELLL] |= armFell
+	} else {
+		f.arms[a/CELLL] |= armJumped
+	}
+}
+
+// how much of a colon definition ran since StartCoverage
+type WordCoverage struct {
+	Name      string
+	Words     int // compiled in it
+	Run       int // of the words compiled
+	Arms      int // of its conditional branches, two each
+	ArmsTaken int
+
+	ca    uint16
+	instr []instr
+}
+
+/*
+Return the coverage of the colon definitions that are not in the image New
+starts from, in the order they were defined.
+*/
+func (f *Forth) Coverage() []WordCoverage {
+	if f.cover == nil {
+		return nil
+	}
+	base := CODEE + CELLL*baseImage().prims
+	dolist, _ := f.Addr("doLIST")
+	na := f._LAST
+	if f.booted {
+		na = f.WordPtr(f.userAddr("LAST"))
+	}
+	var words []WordCoverage
+	for na != 0 && int(na) < EM {
+		n := int(f.Memory[na] & 0x1F)
+		ca := f.WordPtr(na - 2*CELLL)
+		body := ca + 2*CELLL
+		if ca >= base && int(body) < EM-CELLL && f.WordPtr(ca) == CALLL && f.WordPtr(ca+CELLL) == dolist {
+			if first, _, _ := f.nameOf(f.WordPtr(body)); !dataWords[first] {
+				words = append(words, f.wordCoverage(string(f.Memory[na+1:int(na)+1+n]), ca))
+			}
+		}
+		na = f.WordPtr(na - CELLL)
+	}
+	sort.Slice(words, func(i, j int) bool { return words[i].ca < words[j].ca })
+	return words
+}
+
+func (f *Forth) wordCoverage(name string, ca uint16) WordCoverage {
+	w := WordCoverage{Name: name, ca: ca, instr: f.instructions(ca + 2*CELLL)}
+	for _, in := range w.instr {
+		if in.text == "" {
+			continue
+		}
+		w.Words++
+		if f.cover[in.a/CELLL] > 0 {
+			w.Run++
+		}
+		if conditional[in.name] {
+			w.Arms += 2
+			arms := f.arms[in.a/CELLL]
+			for _, arm := range []uint8{armJumped, armFell} {
+				if arms&arm != 0 {
+					w.ArmsTaken++
+				}
+			}
+		}
+	}
+	return w
+}
+
+// return how often in ran and the arm of a branch it never took
+func (f *Forth) annotate(in instr) (uint32, string) {
+	n := f.cover[in.a/CELLL]
+	if !conditional[in.name] {
+		return n, ""
+	}
+	switch arms := f.arms[in.a/CELLL]; {
+	case n == 0:
+		return n, ""
+	case arms&armJumped == 0:
+		return n, "never jumps"
+	case arms&armFell == 0:
+		return n, "never falls through"
+	}
+	return n, ""
+}
+
+// return part of whole in percent
+func percent(part, whole int) int {
+	if whole == 0 {
+		return 100
+	}
+	return part * 100 / whole
+}
+
+/*
+Write how much of every word Coverage returns ran, then the words compiled
+in them with how often they ran, ##### for never, and the branches that
+always went the same way.
+*/
+func (f *Forth) WriteCoverage(w io.Writer) error {
+	words := f.Coverage()
+	p := &errWriter{w: w}
+	for _, c := range words {
+		p.printf("%-24s %4d/%-4d %3d%%  arms %d/%d\n", c.Name, c.Run, c.Words,
+			percent(c.Run, c.Words), c.ArmsTaken, c.Arms)
+	}
+	for _, c := range words {
+		p.printf("\n: %s\n", c.Name)
+		for _, in := range c.instr {
+			if in.text == "" {
+				continue
+			}
+			n, note := f.annotate(in)
+			count := "#####"
+			if n > 0 {
+				count = fmt.Sprint(n)
+			}
+			if note != "" {
+				note = "  <- " + note
+			}
+			p.printf("%9s  %04X  %s%s\n", count, in.a, in.text, note)
+		}
+	}
+	return p.err
+}
+
+// write the same as WriteCoverage as a page of HTML
+func (f *Forth) WriteCoverageHTML(w io.Writer) error {
+	words := f.Coverage()
+	p := &errWriter{w: w}
+	p.printf(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>eForth coverage</title>
+<style>
+body { font-family: monospace }
+td { padding: 0 1em }
+.never { background: #fcc }
+.arm { background: #ffc }
+</style></head><body>
+<table>
+<tr><th>word</th><th>run</th><th></th><th>arms</th></tr>
+`)
+	for _, c := range words {
+		p.printf("<tr><td><a href=\"#%04X\">%s</a></td><td>%d/%d</td><td>%d%%</td><td>%d/%d</td></tr>\n",
+			c.ca, html.EscapeString(c.Name), c.Run, c.Words, percent(c.Run, c.Words), c.ArmsTaken, c.Arms)
+	}
+	p.printf("</table>\n")
+	for _, c := range words {
+		p.printf("<h3 id=\"%04X\">: %s</h3>\n<pre>\n", c.ca, html.EscapeString(c.Name))
+		for _, in := range c.instr {
+			if in.text == "" {
+				continue
+			}
+			n, note := f.annotate(in)
+			line := fmt.Sprintf("%9d  %04X  %s", n, in.a, html.EscapeString(in.text))
+			switch {
+			case n == 0:
+				p.printf("<span class=\"never\">%s</span>\n", line)
+			case note != "":
+				p.printf("<span class=\"arm\" title=\"%s\">%s  &lt;- %s</span>\n", note, line, note)
+			default:
+				p.printf("%s\n", line)
+			}
+		}
+		p.printf("</pre>\n")
+	}
+	p.printf("</body></html>\n")
+	return p.err
+}
+
+// a writer that remembers its first error
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *errWriter) printf(format string, a ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, a...)
+	}
+}
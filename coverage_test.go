@@ -0,0 +1,56 @@
+package eforth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	_, f := NewForth(": sg 0< IF -1 ELSE 1 THEN ;\r: un 2 3 + DROP ;\r" +
+		"VARIABLE v : lp 3 0 DO I v ! LOOP ;\r5 sg DROP lp\rBYE\r")
+	f.StartCoverage()
+	f.Main()
+	good := []WordCoverage{
+		{Name: "sg", Words: 6, Run: 4, Arms: 2, ArmsTaken: 1},
+		{Name: "un", Words: 5, Run: 0},
+		{Name: "lp", Words: 8, Run: 8, Arms: 2, ArmsTaken: 2},
+	}
+	words := f.Coverage()
+	if len(words) != len(good) {
+		t.Fatalf("should cover %d words but covered %+v", len(good), words)
+	}
+	for i, w := range words {
+		g := good[i]
+		if w.Name != g.Name || w.Words != g.Words || w.Run != g.Run ||
+			w.Arms != g.Arms || w.ArmsTaken != g.ArmsTaken {
+			t.Errorf("coverage should be %+v but is %+v", g, w)
+		}
+	}
+
+	b := new(bytes.Buffer)
+	if err := f.WriteCoverage(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"sg                          4/6     66%  arms 1/2",
+		"?branch", "<- never falls through", "#####", ": un"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("the report should have %q but is\n%s", s, b)
+		}
+	}
+	b.Reset()
+	if err := f.WriteCoverageHTML(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`<span class="never">`, `<span class="arm" title="never falls through">`} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("the report should have %q but is\n%s", s, b)
+		}
+	}
+}
+
+func TestNoCoverage(t *testing.T) {
+	if _, f := runForth(": sq DUP * ; 3 sq ."); f.Coverage() != nil {
+		t.Error("there should be no coverage without StartCoverage")
+	}
+}
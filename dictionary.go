@@ -0,0 +1,166 @@
+package eforth
+
+import (
+	"errors"
+	"fmt"
+)
+
+func (f *Forth) addDictionary() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"CHECK", "CHECK", f._Check, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+}
+
+// CHECK ( -- ) display what is wrong with the dictionary, one line each
+func (f *Forth) _Check() {
+	for _, err := range f.CheckDictionary() {
+		f.typeString("\r\n" + err.Error())
+	}
+	f.Next()
+}
+
+// return CP, NP and LAST, from the user area once booted
+func (f *Forth) pointers() (cp, np, last uint16) {
+	a := f.userAddr("CP")
+	if !f.booted {
+		n, _ := f.Addr("ULAST-UZERO")
+		a = n - 3*CELLL
+	}
+	return f.WordPtr(a), f.WordPtr(a + CELLL), f.WordPtr(a + 2*CELLL)
+}
+
+// a dictionary being checked by CheckDictionary
+type dictionaryCheck struct {
+	f      *Forth
+	cp     uint16
+	dolist uint16
+	errs   []error
+}
+
+func (d *dictionaryCheck) report(format string, a ...interface{}) {
+	d.errs = append(d.errs, errors.New(fmt.Sprintf(format, a...)))
+}
+
+// is there the code field of a word at ca, a primitive number or a call
+func (d *dictionaryCheck) codeField(ca uint16) bool {
+	if ca < CODEE || ca >= d.cp || ca%CELLL != 0 {
+		return false
+	}
+	_, ok := d.f.pcode2word[d.f.WordPtr(ca)]
+	return ok
+}
+
+/*
+Check the name at na and the code of its word, a colon word calls
+a primitive and its body holds code fields.
+*/
+func (d *dictionaryCheck) word(na uint16) {
+	f := d.f
+	l := f.Memory[na]
+	n := uint16(l & 0x1F)
+	if n == 0 {
+		d.report("name at %X has no length", na)
+		return
+	}
+	if flags := uint16(l) &^ (MASKK & 0xFF); flags&^(COMPO|IMEDD) != 0 {
+		d.report("name at %X has lexicon bits %X", na, flags)
+	}
+	if int(na)+1+int(n) > NAMEE {
+		d.report("name at %X goes past the name dictionary", na)
+		return
+	}
+	name := string(f.Memory[na+1 : na+1+n])
+	for i := 0; i < len(name); i++ {
+		if name[i] <= ' ' || i == 0 && name[i] > MASKK>>8 {
+			d.report("name %q at %X has the character %X", name, na, name[i])
+			break
+		}
+	}
+	ca := f.WordPtr(na - 2*CELLL)
+	if !d.codeField(ca) {
+		d.report("%s has the code address %X, not a code field below CP %X", name, ca, d.cp)
+		return
+	}
+	if f.WordPtr(ca) != CALLL || ca == f.prim2addr["CALL"] {
+		return
+	}
+	called := f.WordPtr(ca + CELLL)
+	if !d.codeField(called) {
+		d.report("%s calls %X, not a code field", name, called)
+		return
+	}
+	if called != d.dolist || ca+2*CELLL >= d.cp {
+		return
+	}
+	body := ca + 2*CELLL
+	if first, _, _ := f.nameOf(f.WordPtr(body)); dataWords[first] {
+		return
+	}
+	var end uint16 // the last address a branch goes to
+	for _, in := range f.instructions(body) {
+		if d.wordStart(in.a) {
+			return // the definition ended with a word that does not return
+		}
+		if in.name == "" && in.text != "" && !d.codeField(f.WordPtr(in.a)) {
+			d.report("%s has %X at %X, not a code field", name, f.WordPtr(in.a), in.a)
+		}
+		if branches[in.name] {
+			end = max(end, f.WordPtr(in.a+CELLL))
+			if in.name == "branch" && in.a+2*CELLL > end {
+				return // nothing after it runs
+			}
+		}
+	}
+}
+
+// does the code field of a primitive or of a word calling one start at a
+func (d *dictionaryCheck) wordStart(a uint16) bool {
+	c := d.f.WordPtr(a)
+	if c == CALLL {
+		return d.codeField(d.f.WordPtr(a + CELLL))
+	}
+	return d.codeField(a) && a == CODEE+CELLL*(c-1)
+}
+
+/*
+Return what is wrong with the dictionary: the pointers out of order, names
+linked from LAST outside the name dictionary, with a bad length or lexicon
+bits, code addresses that are not code fields below CP and colon
+definitions with cells in their body that are not.  A bad ! can leave
+these, nothing is returned for a sound dictionary.
+*/
+func (f *Forth) CheckDictionary() []error {
+	f.boot()
+	cp, np, last := f.pointers()
+	dolist, _ := f.Addr("doLIST")
+	d := &dictionaryCheck{f: f, cp: cp, dolist: dolist}
+	if cp < CODEE || cp >= np || np > NAMEE {
+		d.report("CP %X and NP %X are not in order between %X and %X", cp, np, CODEE, NAMEE)
+		if cp > NAMEE {
+			d.cp = NAMEE
+		}
+	}
+	for na := last; na != 0; {
+		if na < np || na >= NAMEE || na < 2*CELLL {
+			d.report("name at %X is outside the name dictionary", na)
+			break
+		}
+		d.word(na)
+		next := f.WordPtr(na - CELLL)
+		if next != 0 && next <= na {
+			d.report("name at %X links to %X, not to an older name", na, next)
+			break
+		}
+		na = next
+	}
+	return d.errs
+}
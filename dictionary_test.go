@@ -0,0 +1,41 @@
+package eforth
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCheckDictionary(t *testing.T) {
+	if errs := New(nil, io.Discard).CheckDictionary(); len(errs) != 0 {
+		t.Errorf("the kernel should check but returned %v", errs)
+	}
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{": sq DUP * ;", ""},
+		{"0 ' DUP >NAME C!", "has no length"},
+		{"' DUP >NAME DUP C@ $20 OR SWAP C!", "has lexicon bits 20"},
+		{"BL ' DUP >NAME 1 + C!", `name " UP" at 3D26 has the character 20`},
+		{": sq DUP * ; 0 ' sq >NAME 4 - !", "sq has the code address 0, not a code field below CP"},
+		{": sq DUP * ; 5 ' sq 2 + !", "sq calls 5, not a code field"},
+		{": sq DUP * ; 5 ' sq 6 + !", "sq has 5 at"},
+		{"NP @ 2 + CP !", "are not in order"},
+	}
+	for _, v := range tests {
+		o, f := NewForth(v.src + " CHECK\rBYE\r")
+		f.Main()
+		if v.good == "" && strings.Contains(o.String(), "code field") || !strings.Contains(o.String(), v.good) {
+			t.Errorf("%s should CHECK %q but printed %q", v.src, v.good, o)
+		}
+	}
+	// a name linked to itself hangs the text interpreter looking for CHECK
+	f := New(nil, io.Discard)
+	f.boot()
+	last := f.WordPtr(f.userAddr("LAST"))
+	f.SetWordPtr(last-CELLL, last)
+	if errs := f.CheckDictionary(); len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), "not to an older name") {
+		t.Errorf("a name linked to itself should check as one but returned %v", errs)
+	}
+}
@@ -0,0 +1,299 @@
+package eforth
+
+import (
+	"fmt"
+	"math"
+)
+
+func (f *Forth) addDouble() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"NUMBER?", "DNUMQ", f._NumberQ, 0},
+		{"D2/", "DTWOS", f._D2slash, 0},
+		{"M*/", "MSTSL", f._MstarSlash, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Double numbers
+
+;   DPL		( -- a )
+;		Hold the digits after the point of the last number converted, -1 if none.
+
+		$COLON	3,'DPL',DPL
+		DW	DOVAR
+		DW	-1
+
+;   2SWAP	( d1 d2 -- d2 d1 )
+;		Exchange top two doubles.
+
+		$COLON	5,'2SWAP',DSWAP
+		DW	ROT,TOR,ROT,RFROM,EXIT
+
+;   2OVER	( d1 d2 -- d1 d2 d1 )
+;		Copy second double to top.
+
+		$COLON	5,'2OVER',DOVER
+		DW	TOR,TOR,DDUP,RFROM,RFROM,DSWAP,EXIT
+
+;   D-		( d1 d2 -- d1-d2 )
+;		Double subtraction.
+
+		$COLON	2,'D-',DSUB
+		DW	DNEGA,DPLUS,EXIT
+
+;   M+		( d n -- d )
+;		Add a single to a double.
+
+		$COLON	2,'M+',MPLUS
+		DW	DUPP,ZLESS,DPLUS,EXIT
+
+;   D2*		( d -- d )
+;		Double shift left.
+
+		$COLON	3,'D2*',DTWOST
+		DW	DDUP,DPLUS,EXIT
+
+;   DABS	( d -- ud )
+;		Return the absolute value of a double.
+
+		$COLON	4,'DABS',DABS
+		DW	DUPP,ZLESS
+		DW	QBRAN,DABS1
+		DW	DNEGA
+DABS1:		DW	EXIT
+
+;   D0=		( d -- t )
+;		Return true if the double is zero.
+
+		$COLON	3,'D0=',DZEQU
+		DW	ORR,DOLIT,0,EQUAL,EXIT
+
+;   D=		( d1 d2 -- t )
+;		Return true if top two doubles are equal.
+
+		$COLON	2,'D=',DEQUA
+		DW	DSUB,DZEQU,EXIT
+
+;   D<		( d1 d2 -- t )
+;		Signed compare of top two doubles.
+
+		$COLON	2,'D<',DLESS
+		DW	ROT,DDUP,EQUAL		;?high cells equal
+		DW	QBRAN,DLES1
+		DW	DDROP,ULESS,EXIT	;yes, compare low cells
+DLES1:		DW	SWAP,LESS,TOR,DDROP,RFROM,EXIT
+
+;   DMAX	( d1 d2 -- d )
+;		Return the greater of two doubles.
+
+		$COLON	4,'DMAX',DMAX
+		DW	DOVER,DOVER,DLESS
+		DW	QBRAN,DMAX1
+		DW	DSWAP
+DMAX1:		DW	DDROP,EXIT
+
+;   DMIN	( d1 d2 -- d )
+;		Return the smaller of two doubles.
+
+		$COLON	4,'DMIN',DMIN
+		DW	DOVER,DOVER,DLESS
+		DW	QBRAN,DMIN1
+		DW	DDROP,EXIT
+DMIN1:		DW	DSWAP,DDROP,EXIT
+
+;   d#		( ud -- ud )
+;		Extract one digit from ud and append the digit to output string.
+
+		$COLON	2,'d#',DDIG
+		DW	DOLIT,0,BASE,AT,UMMOD,TOR	;divide high cell
+		DW	BASE,AT,UMMOD			;divide low cell and remainder
+		DW	RFROM,ROT,DIGIT,HOLD,EXIT
+
+;   d#S		( ud -- 0 0 )
+;		Convert ud until all digits are added to the output string.
+
+		$COLON	3,'d#S',DDIGS
+DDIGS1:		DW	DDIG,DDUP,ORR
+		DW	QBRAN,DDIGS2
+		DW	BRAN,DDIGS1
+DDIGS2:		DW	EXIT
+
+;   dstr	( d -- b u )
+;		Convert a signed double to a numeric string.
+
+		$COLON	4,'dstr',DSTR
+		DW	DUPP,TOR,DABS
+		DW	BDIGS,DDIGS,RFROM
+		DW	SIGN,DROP,EDIGS,EXIT
+
+;   D.R		( d +n -- )
+;		Display a double in a field of n columns, right justified.
+
+		$COLON	3,'D.R',DDOTR
+		DW	TOR,DSTR,RFROM,OVER,SUBB
+		DW	SPACS,TYPEE,EXIT
+
+;   D.		( d -- )
+;		Display a double in free format, preceeded by a space.
+
+		$COLON	2,'D.',DDOT
+		DW	DSTR,SPACE,TYPEE,EXIT
+
+;   2LITERAL	( d -- )
+;		Compile a double literal.
+
+		$COLON	IMEDD+8,'2LITERAL',DLITE
+		DW	SWAP,LITER,LITER,EXIT
+
+;   do2CON	( -- d )
+;		Run time routine for 2CONSTANT.
+
+		$COLON	COMPO+6,'do2CON',DODCON
+		DW	RFROM,DAT,EXIT
+
+;   2CONSTANT	( d -- ; <string> )
+;		Compile a new double constant.
+
+		$COLON	9,'2CONSTANT',DCONS
+		DW	TOKEN,SNAME,OVERT
+		DW	DOLIT,DOLST,CALLC
+		DW	COMPI,DODCON,COMMA,COMMA,EXIT
+
+;   2VARIABLE	( -- ; <string> )
+;		Compile a new double variable initialized to 0.
+
+		$COLON	9,'2VARIABLE',DVARI
+		DW	CREAT,DOLIT,0,DUPP
+		DW	COMMA,COMMA,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+func (f *Forth) pushDouble(d int32) {
+	f.Push(uint16(d))
+	f.Push(uint16(uint32(d) >> 16))
+}
+
+func (f *Forth) popDouble() int32 {
+	hi := f.Pop()
+	lo := f.Pop()
+	return int32(uint32(hi)<<16 | uint32(lo))
+}
+
+/*
+Convert s to a number in base the way NUMBER? does.  A leading $ selects
+hex and a leading - negates.  A point anywhere makes a double and dpl is
+the number of digits after it, or -1 for a single.  A number that does not
+fit in a cell, or in two for a double, signed or unsigned, is not one.
+*/
+func parseNumber(s string, base int) (n int32, dpl int, ok bool) {
+	if len(s) > 0 && s[0] == '$' {
+		base = 16
+		s = s[1:]
+	}
+	neg := false
+	if len(s) > 0 && s[0] == '-' {
+		neg = true
+		s = s[1:]
+	}
+	var u uint64
+	dpl = -1
+	digits := 0
+	for _, c := range s {
+		if c == '.' {
+			if dpl >= 0 {
+				return 0, -1, false
+			}
+			dpl = 0
+			continue
+		}
+		v := int(c) - '0' // same as DIGIT?, upper case letters only
+		if v > 9 {
+			v -= 7
+			if v < 10 {
+				return 0, -1, false
+			}
+		}
+		if v < 0 || v >= base {
+			return 0, -1, false
+		}
+		u = u*uint64(base) + uint64(v)
+		if u > math.MaxUint32 {
+			return 0, -1, false
+		}
+		digits += 1
+		if dpl >= 0 {
+			dpl += 1
+		}
+	}
+	max := uint64(math.MaxUint16)
+	if dpl >= 0 {
+		max = math.MaxUint32
+	}
+	if digits == 0 || u > max || neg && u > (max+1)/2 {
+		return 0, -1, false
+	}
+	n = int32(u)
+	if neg {
+		n = -n
+	}
+	return n, dpl, true
+}
+
+// NUMBER? ( a -- n T | d T | a F ) convert a number string to a single, or to
+// a double if it has a point, and set DPL
+func (f *Forth) _NumberQ() {
+	a := f.Pop()
+	base := int(f.WordPtr(f.userAddr("BASE")))
+	n, dpl, ok := parseNumber(f.countedString(a), base)
+	if !ok {
+		f.Push(a)
+		f.Push(0)
+		f.Next()
+		return
+	}
+	f.SetWordPtr(f.varAddr("DPL"), asuint16(int16(dpl)))
+	if dpl < 0 {
+		f.Push(uint16(n))
+	} else {
+		f.pushDouble(n)
+	}
+	f.Push(asuint16(-1))
+	f.Next()
+}
+
+// D2/ ( d -- d ) double arithmetic shift right
+func (f *Forth) _D2slash() {
+	f.pushDouble(f.popDouble() >> 1)
+	f.Next()
+}
+
+// M*/ ( d1 n1 +n2 -- d2 ) multiply d1 by n1 and divide by n2 with a triple
+// intermediate result, floored like M/MOD
+func (f *Forth) _MstarSlash() {
+	n2 := int64(asint16(f.Pop()))
+	n1 := int64(asint16(f.Pop()))
+	d := int64(f.popDouble())
+	if n2 == 0 {
+		f.throwMessage("division by zero")
+		return
+	}
+	p := d * n1
+	q := p / n2
+	if p%n2 != 0 && (p < 0) != (n2 < 0) {
+		q -= 1
+	}
+	f.pushDouble(int32(q))
+	f.Next()
+}
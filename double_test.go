@@ -0,0 +1,97 @@
+package eforth
+
+import (
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		base int
+		n    int32
+		dpl  int
+		ok   bool
+	}{
+		{"123", 10, 123, -1, true},
+		{"-123", 10, -123, -1, true},
+		{"123.", 10, 123, 0, true},
+		{"12.34", 10, 1234, 2, true},
+		{"-100000.", 10, -100000, 0, true},
+		{"$FF", 10, 255, -1, true},
+		{"$-10.", 10, -16, 0, true},
+		{"FF", 16, 255, -1, true},
+		{"ff", 16, 0, -1, false},
+		{"12", 2, 0, -1, false},
+		{"1.2.", 10, 0, -1, false},
+		{".", 10, 0, -1, false},
+		{"-", 10, 0, -1, false},
+		{"", 10, 0, -1, false},
+		{"65535", 10, 65535, -1, true},
+		{"-32768", 10, -32768, -1, true},
+		{"65536", 10, 0, -1, false},
+		{"-32769", 10, 0, -1, false},
+		{"99999999999", 10, 0, -1, false},
+		{"4294967295.", 10, -1, 0, true},
+		{"-2147483648.", 10, -2147483648, 0, true},
+		{"4294967296.", 10, 0, -1, false},
+		{"-2147483649.", 10, 0, -1, false},
+	}
+	for _, v := range tests {
+		n, dpl, ok := parseNumber(v.s, v.base)
+		if ok != v.ok || ok && (n != v.n || dpl != v.dpl) {
+			t.Error(v.s, "converted to", n, dpl, ok, "instead of", v.n, v.dpl, v.ok)
+		}
+	}
+}
+
+func TestDoubleLiterals(t *testing.T) {
+	o, f := NewForth("100000. -7. DPL @ 12.34 DPL @ BYE\r")
+	f.Main()
+	t.Log(o.String())
+	if x := f.Pop(); x != 2 {
+		t.Fatal("DPL should be 2 after 12.34 but is", x)
+	}
+	if d := f.popDouble(); d != 1234 {
+		t.Fatal("12.34 should be the double 1234 but is", d)
+	}
+	if x := f.Pop(); x != 0 {
+		t.Fatal("DPL should be 0 after -7. but is", x)
+	}
+	if d := f.popDouble(); d != -7 {
+		t.Fatal("-7. should be -7 but is", d)
+	}
+	if d := f.popDouble(); d != 100000 {
+		t.Fatal("100000. should be 100000 but is", d)
+	}
+}
+
+func TestDoubleWords(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"100000. D.", " 100000"},
+		{"-100000. D.", " -100000"},
+		{"70000. 70000. D+ D.", " 140000"},
+		{"1. 100000. D- D.", " -99999"},
+		{"-65536. DABS D.", " 65536"},
+		{"1. 2. D< . 2. 1. D< . -1. 1. D< . 1. 1. D< .", " -1 0 -1 0"},
+		{"5. 5. D= . 5. 6. D= . 0. D0= . 65536. D0= .", " -1 0 -1 0"},
+		{"3. 100000. DMAX D. 3. 100000. DMIN D.", " 100000 3"},
+		{"-7. D2/ D. 40000. D2* D.", " -4 80000"},
+		{"1. -1 M+ D. 65535. 1 M+ D.", " 0 65536"},
+		{"100000. 3 2 M*/ D. -7. 1 2 M*/ D.", " 150000 -4"},
+		{"123. 8 D.R", "     123"},
+		{"70000. 2CONSTANT big big D.", " 70000"},
+		{"2VARIABLE dv 80000. dv 2! dv 2@ D.", " 80000"},
+		{": dl 100000. -3. ; dl D. D.", " -3 100000"},
+		{": d2 [ 90000. ] 2LITERAL ; d2 D.", " 90000"},
+		{"1 2 3 4 2SWAP . . . . 1 2 3 4 2OVER . . . . . .", " 2 1 4 3 2 1 4 3 2 1"},
+		{"HEX -10. D. DECIMAL", " -10"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); out != v.good {
+			t.Error(v.src, "should print", v.good, "but printed", out)
+		}
+	}
+}
@@ -0,0 +1,568 @@
+package eforth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// a stack comment in a listing, like ";   ROT		( w1 w2 w3 -- w2 w3 w1 )"
+var stackCommentRe = regexp.MustCompile(`^;\s+\S+\s+(\(\s.*--.*\))\s*$`)
+
+// items of a stack comment that are two cells, like d, ud2 or d1-d2
+var doubleItem = regexp.MustCompile(`^-?u?d[0-9]*(-u?d[0-9]*)?$`)
+
+// words whose colon definitions start with one of these are data, not code
+var dataWords = map[string]bool{"doVAR": true, "doUSER": true, "doVOC": true,
+	"doCON": true, "doVAL": true, "doDEFER": true, "do2CON": true, "doFCON": true}
+
+// words that never return to the word calling them
+var noReturn = map[string]bool{"THROW": true, "ABORT": true, "QUIT": true,
+	"COLD": true, "BYE": true}
+
+// return the stack comment of a line of a listing, or ""
+func listingComment(line string) string {
+	if m := stackCommentRe.FindStringSubmatch(line); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// keep the stack comment of the word just added, for CheckEffects
+func (f *Forth) declare(name, effect string) {
+	if f.binding || effect == "" {
+		return
+	}
+	f.own()
+	f.effects[f.prim2addr[name]] = effect
+}
+
+// what is known of the cell on top of the data stack
+const (
+	topUnknown = iota
+	topZero
+	topTrue
+)
+
+// the cells a word takes from the data stack and, for each way it can end,
+// the cells it leaves and what is known of the top one
+type effect struct {
+	in   int
+	outs []int
+	tops []int
+}
+
+// return the effect of a stack comment like ( w -- w w | 0 ), the part
+// after ; or ) like F: r -- is not about the data stack
+func parseEffect(s string) (effect, error) {
+	var e effect
+	items := strings.Fields(strings.TrimPrefix(s, "("))
+	for i, it := range items {
+		if it == ";" || it == ")" || it == "\\" {
+			items = items[:i]
+			break
+		}
+	}
+	cells := func(items []string) (int, bool) {
+		n := 0
+		for _, it := range items {
+			switch {
+			case strings.HasPrefix(it, ".."):
+				return 0, false
+			case doubleItem.MatchString(it):
+				n += 2
+			default:
+				n++
+			}
+		}
+		return n, true
+	}
+	dashes := -1
+	for i, it := range items {
+		if it == "--" {
+			dashes = i
+			break
+		}
+	}
+	if dashes < 0 {
+		return e, errors.New(fmt.Sprintf("can not follow the stack comment %s", s))
+	}
+	in, ok := cells(items[:dashes])
+	if !ok {
+		return e, errors.New(fmt.Sprintf("can not follow the stack comment %s", s))
+	}
+	e.in = in
+	zero := false
+	var alts [][]string
+	alt := []string{}
+	for _, it := range append(items[dashes+1:], "|") {
+		if it != "|" {
+			alt = append(alt, it)
+			continue
+		}
+		if len(alt) > 0 && (alt[len(alt)-1] == "0" || alt[len(alt)-1] == "F") {
+			zero = true
+		}
+		alts, alt = append(alts, alt), []string{}
+	}
+	for _, alt := range alts {
+		n, ok := cells(alt)
+		if !ok {
+			return e, errors.New(fmt.Sprintf("can not follow the stack comment %s", s))
+		}
+		top := topUnknown
+		switch {
+		case !zero || len(alt) == 0:
+		case alt[len(alt)-1] == "0" || alt[len(alt)-1] == "F":
+			top = topZero
+		default:
+			top = topTrue
+		}
+		e.outs, e.tops = append(e.outs, n), append(e.tops, top)
+	}
+	return e, nil
+}
+
+// the stacks at a point of a colon definition, relative to where it started
+type stackState struct {
+	at    uint16   // the next instruction
+	depth int      // data stack cells
+	min   int      // the fewest data stack cells there were
+	r     int      // return stack cells pushed
+	top   int      // what is known of the top cell
+	loops []uint16 // where LEAVE goes in the loops entered, innermost last
+}
+
+func (s stackState) key() string {
+	return fmt.Sprint(s.at, s.depth, s.min, s.r, s.top, s.loops)
+}
+
+// take n cells from the data stack
+func (s stackState) pop(n int) stackState {
+	s.depth -= n
+	if s.depth < s.min {
+		s.min = s.depth
+	}
+	s.top = topUnknown
+	return s
+}
+
+// put n cells on the data stack, the top one known to be top
+func (s stackState) push(n, top int) stackState {
+	s.depth += n
+	s.top = top
+	return s
+}
+
+// add n cells to the return stack, entering or leaving a loop if leave is
+// not 0 or n is -3
+func (s stackState) rpush(n int, leave uint16) stackState {
+	s.r += n
+	if leave != 0 {
+		s.loops = append(s.loops[:len(s.loops):len(s.loops)], leave)
+	} else if n == -3 {
+		s.loops = s.loops[:len(s.loops)-1]
+	}
+	return s
+}
+
+// the most ways through a colon definition that are followed
+const maxPaths = 10000
+
+// what a word does depends on the word it executes
+var errExecutes = errors.New("executes a word it is given")
+
+// follows colon definitions, remembering the effects found for the words
+type effectChecker struct {
+	f         *Forth
+	dolist    uint16
+	following map[uint16]bool // the definitions being followed
+	found     map[uint16]effectFound
+}
+
+type effectFound struct {
+	e   effect
+	err error
+}
+
+func (f *Forth) newEffectChecker() *effectChecker {
+	dolist, _ := f.Addr("doLIST")
+	return &effectChecker{f: f, dolist: dolist,
+		following: make(map[uint16]bool), found: make(map[uint16]effectFound)}
+}
+
+// return whether the word at ca is a colon definition
+func (c *effectChecker) colon(ca uint16) bool {
+	return int(ca) < EM-2*CELLL && c.f.WordPtr(ca) == CALLL && c.f.WordPtr(ca+CELLL) == c.dolist
+}
+
+/*
+Follow every way through the colon definition at ca and return the stacks
+at each EXIT, or an error telling why it can not be followed: it uses a
+word with no stack effect, takes its return address or branches out of
+its body.
+*/
+func (c *effectChecker) follow(ca uint16) ([]stackState, error) {
+	f := c.f
+	is := f.instructions(ca + 2*CELLL)
+	index := make(map[uint16]int, len(is))
+	for i, in := range is {
+		index[in.a] = i
+	}
+	fail := func(format string, a ...interface{}) ([]stackState, error) {
+		return nil, errors.New(fmt.Sprintf(format, a...))
+	}
+	var ends []stackState
+	done := make(map[string]bool)
+	todo := []stackState{{at: ca + 2*CELLL}}
+	for len(todo) > 0 {
+		s := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if done[s.key()] {
+			continue
+		}
+		if done[s.key()] = true; len(done) > maxPaths {
+			return fail("too many ways through it")
+		}
+		i, ok := index[s.at]
+		if !ok {
+			return fail("it goes to %X out of its body", s.at)
+		}
+		in := is[i]
+		var next uint16
+		if i+1 < len(is) {
+			next = is[i+1].a
+		}
+		to := f.WordPtr(in.a + CELLL) // of branches
+		goes := func(s stackState, a uint16) {
+			s.at = a
+			todo = append(todo, s)
+		}
+		inLoop := len(s.loops) > 0 && s.r >= 3
+		switch name := in.name; {
+		case name == "":
+			return fail("%s is not a word", in.text)
+		case name == "EXIT":
+			ends = append(ends, s)
+		case name == "branch":
+			goes(s, to)
+		case name == "?branch":
+			t := s.top
+			s = s.pop(1)
+			if t != topTrue {
+				goes(s, to)
+			}
+			if t != topZero {
+				goes(s, next)
+			}
+		case name == "next":
+			if s.r < 1 {
+				return fail("next without FOR")
+			}
+			goes(s, to)
+			goes(s.rpush(-1, 0), next)
+		case name == "(do)":
+			goes(s.pop(2).rpush(3, to), next)
+		case name == "(?do)":
+			goes(s.pop(2), to)
+			goes(s.pop(2).rpush(3, to), next)
+		case (name == "(loop)" || name == "(+loop)") && inLoop:
+			if name == "(+loop)" {
+				s = s.pop(1)
+			}
+			goes(s, to)
+			goes(s.rpush(-3, 0), next)
+		case name == "LEAVE" && inLoop:
+			goes(s.rpush(-3, 0), s.loops[len(s.loops)-1])
+		case name == "UNLOOP" && inLoop:
+			goes(s.rpush(-3, 0), next)
+		case name == "I" || name == "J":
+			goes(s.push(1, topUnknown), next)
+		case name == "doLIT":
+			top := topTrue
+			if to == 0 {
+				top = topZero
+			}
+			goes(s.push(1, top), next)
+		case name == ">R":
+			goes(s.pop(1).rpush(1, 0), next)
+		case (name == "R>" || name == "R@") && s.r > 0:
+			if name == "R>" {
+				s = s.rpush(-1, 0)
+			}
+			goes(s.push(1, topUnknown), next)
+		case name == "R>" || name == "R@":
+			return fail("%s takes its return address", name)
+		case name == "DUP":
+			goes(s.pop(1).push(2, s.top), next)
+		case name == "doFLIT" || name == "(unlocal)":
+			goes(s, next)
+		case name == "(locals)":
+			goes(s.pop(int(to)), next)
+		case name == "local@":
+			goes(s.push(1, topUnknown), next)
+		case name == "local!" || name == "local+!" || name == "value!" || name == "value+!":
+			goes(s.pop(1), next)
+		case noReturn[name]:
+		default:
+			e, err := c.effectOf(f.WordPtr(in.a))
+			if err == errExecutes {
+				return nil, err
+			}
+			if err != nil {
+				return fail("%s: %v", in.text, err)
+			}
+			s = s.pop(e.in)
+			for k, n := range e.outs {
+				goes(s.push(n, e.tops[k]), next)
+			}
+		}
+	}
+	return ends, nil
+}
+
+/*
+Return the stack effect of the word at ca, the one declared for it or else
+the one found following its colon definition.  A word that executes words
+it is given, like @EXECUTE, has none whatever its stack comment says.
+*/
+func (c *effectChecker) effectOf(ca uint16) (effect, error) {
+	if r, ok := c.found[ca]; ok {
+		return r.e, r.err
+	}
+	e, err := c.find(ca)
+	c.found[ca] = effectFound{e, err}
+	return e, err
+}
+
+func (c *effectChecker) find(ca uint16) (effect, error) {
+	f := c.f
+	declared, has := f.effects[ca]
+	if !c.colon(ca) {
+		switch name, _, _ := f.nameOf(ca); {
+		case name == "EXECUTE":
+			return effect{}, errExecutes
+		case !has:
+			return effect{}, errors.New("no stack effect")
+		}
+		return parseEffect(declared)
+	}
+	switch first, _, _ := f.nameOf(f.WordPtr(ca + 2*CELLL)); {
+	case first == "doDEFER":
+		return effect{}, errExecutes
+	case dataWords[first]:
+		return c.effectOf(f.WordPtr(ca + 2*CELLL))
+	}
+	if c.following[ca] {
+		if has {
+			return parseEffect(declared)
+		}
+		return effect{}, errors.New("recursive")
+	}
+	c.following[ca] = true
+	defer delete(c.following, ca)
+	ends, err := c.follow(ca)
+	switch {
+	case err == errExecutes:
+		return effect{}, err
+	case has:
+		return parseEffect(declared)
+	case err != nil:
+		return effect{}, err
+	}
+	var e effect
+	for _, s := range ends {
+		if -s.min > e.in {
+			e.in = -s.min
+		}
+	}
+	found := make(map[[2]int]bool)
+	for _, s := range ends {
+		if k := [2]int{s.depth + e.in, s.top}; !found[k] {
+			found[k] = true
+			e.outs, e.tops = append(e.outs, k[0]), append(e.tops, k[1])
+		}
+	}
+	return e, nil
+}
+
+// a colon definition whose body does not do what its stack comment says
+type EffectError struct {
+	Name     string
+	Declared string // the stack comment
+	Found    string // the cells the body takes and leaves, like ( 2 -- 1 | 0 )
+}
+
+func (e EffectError) Error() string {
+	return fmt.Sprintf("%s %s but its body does %s", e.Name, e.Declared, e.Found)
+}
+
+// return an EffectError if the colon definition at ca does not do what
+// was declared for it, nil if it does or if it can not be followed
+func (c *effectChecker) check(name string, ca uint16) *EffectError {
+	declared, err := parseEffect(c.f.effects[ca])
+	if err != nil {
+		return nil
+	}
+	c.following[ca] = true
+	ends, err := c.follow(ca)
+	delete(c.following, ca)
+	if err != nil {
+		return nil
+	}
+	in, bad := 0, false
+	for _, s := range ends {
+		if -s.min > in {
+			in = -s.min
+		}
+		matches := false
+		for _, n := range declared.outs {
+			matches = matches || s.depth == n-declared.in
+		}
+		bad = bad || !matches || s.r != 0 || -s.min > declared.in
+	}
+	if !bad {
+		return nil
+	}
+	var outs []string
+	seen := make(map[string]bool)
+	for _, s := range ends {
+		o := fmt.Sprint(s.depth + in)
+		if s.r != 0 {
+			o += fmt.Sprintf(" R: %d", s.r)
+		}
+		if !seen[o] {
+			seen[o] = true
+			outs = append(outs, o)
+		}
+	}
+	sort.Strings(outs)
+	return &EffectError{Name: name, Declared: c.f.effects[ca],
+		Found: fmt.Sprintf("( %d -- %s )", in, strings.Join(outs, " | "))}
+}
+
+/*
+Return the colon definitions in the dictionary whose bodies do not do what
+their stack comments say, in the order they were defined.  The stack
+comments are those of the listings, of Bind and of CheckSource.
+
+Every way through a body is followed counting the cells it takes and
+leaves, taking the words it uses to do what their stack comments say, the
+zero of ?DUP ( w -- w w | 0 ) going to ELSE.  Definitions that execute a
+word they are given, like EMIT, or that take their return address are not
+checked.
+*/
+func (f *Forth) CheckEffects() []EffectError {
+	f.boot()
+	c := f.newEffectChecker()
+	var errs []EffectError
+	for na := f.WordPtr(f.userAddr("LAST")); na != 0 && int(na) < EM; na = f.WordPtr(na - CELLL) {
+		ca := f.WordPtr(na - 2*CELLL)
+		if _, ok := f.effects[ca]; !ok || !c.colon(ca) {
+			continue
+		}
+		if first, _, _ := f.nameOf(f.WordPtr(ca + 2*CELLL)); dataWords[first] {
+			continue
+		}
+		name := string(f.Memory[na+1 : int(na)+1+int(f.Memory[na]&0x1F)])
+		if e := c.check(name, ca); e != nil {
+			errs = append([]EffectError{*e}, errs...)
+		}
+	}
+	return errs
+}
+
+// the name and stack comment of a colon definition in Forth source
+type sourceEffect struct {
+	name, effect string
+}
+
+// return the colon definitions of src that have a stack comment right
+// after their names, skipping comments and strings
+func sourceEffects(src string) []sourceEffect {
+	var decls []sourceEffect
+	i := 0
+	token := func() string {
+		for i < len(src) && src[i] <= ' ' {
+			i++
+		}
+		j := i
+		for i < len(src) && src[i] > ' ' {
+			i++
+		}
+		return src[j:i]
+	}
+	upto := func(c byte) string {
+		j := strings.IndexByte(src[i:], c)
+		if j < 0 {
+			s := src[i:]
+			i = len(src)
+			return s
+		}
+		s := src[i : i+j]
+		i += j + 1
+		return s
+	}
+	for i < len(src) {
+		switch t := token(); {
+		case t == `\`:
+			upto('\n')
+		case t == "(" || t == ".(":
+			upto(')')
+		case strings.HasSuffix(t, `"`):
+			upto('"')
+		case t == ":":
+			name, at := token(), i
+			if token() == "(" {
+				c := "( " + strings.Join(strings.Fields(upto(')')), " ") + " )"
+				decls = append(decls, sourceEffect{name, c})
+			} else {
+				i = at
+			}
+		}
+	}
+	return decls
+}
+
+/*
+Interpret the Forth source read from r like Include and return the colon
+definitions in it whose bodies do not do what their stack comments say,
+see CheckEffects.  The stack comment of a definition is the ( ... ) right
+after its name, like : SQ ( n -- n ) DUP * ;
+*/
+func (f *Forth) CheckSource(name string, r io.Reader) ([]EffectError, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Include(name, bytes.NewReader(src)); err != nil {
+		return nil, err
+	}
+	decls := sourceEffects(string(src))
+	c := f.newEffectChecker()
+	defined := make(map[string]uint16)
+	for _, d := range decls {
+		if ca, ok := f.lookup(d.name); ok && c.colon(ca) {
+			f.own()
+			f.effects[ca] = d.effect
+			defined[d.name] = ca
+		}
+	}
+	var errs []EffectError
+	for _, d := range decls {
+		ca, ok := defined[d.name]
+		if !ok {
+			continue
+		}
+		delete(defined, d.name)
+		if e := c.check(d.name, ca); e != nil {
+			errs = append(errs, *e)
+		}
+	}
+	return errs, nil
+}
@@ -0,0 +1,88 @@
+package eforth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKernelEffects(t *testing.T) {
+	// IS takes ca only when interpreting, compiling it takes none
+	known := map[string]bool{"IS": true}
+	for _, e := range New(nil, nil).CheckEffects() {
+		if !known[e.Name] {
+			t.Error(e)
+		}
+	}
+}
+
+func TestParseEffect(t *testing.T) {
+	tests := []struct {
+		s    string
+		in   int
+		outs []int
+	}{
+		{"( w -- w w | 0 )", 1, []int{2, 1}},
+		{"( d1 d2 -- d1-d2 )", 4, []int{2}},
+		{"( udl udh u -- ur uq )", 3, []int{2}},
+		{"( a -- n T | d T | T | a F ; F: -- r )", 1, []int{2, 3, 1, 2}},
+		{"( b u -- b u | )", 2, []int{2, 0}},
+		{"( n -- c-addr u ) ( F: r -- )", 1, []int{2}},
+		{"( a a u -- a a f \\ -0+ )", 3, []int{3}},
+	}
+	for _, v := range tests {
+		e, err := parseEffect(v.s)
+		if err != nil || e.in != v.in || len(e.outs) != len(v.outs) {
+			t.Errorf("%s should take %d and leave %v but returned %+v, %v", v.s, v.in, v.outs, e, err)
+			continue
+		}
+		for i := range v.outs {
+			if e.outs[i] != v.outs[i] {
+				t.Errorf("%s should take %d and leave %v but returned %+v", v.s, v.in, v.outs, e)
+			}
+		}
+	}
+	for _, s := range []string{"( -- ; <string> )", "( 0 A .. A -- )", "( a b )"} {
+		if _, err := parseEffect(s); (err == nil) != (s == "( -- ; <string> )") {
+			t.Errorf("parsing %s returned %v", s, err)
+		}
+	}
+}
+
+func TestCheckSource(t *testing.T) {
+	src := `\ good ones
+: sq ( n -- n ) DUP * ;
+: cube ( n -- n ) DUP sq * ;
+: sum ( n -- n ) 0 SWAP 0 ?DO I + LOOP ;
+: nz ( w -- ) ?DUP IF DROP THEN ;
+: cnt ( n -- n ) 0 SWAP FOR 1 + NEXT ;
+: emits ( c -- ) EMIT ;
+: tell ( -- ) ." a : x ( -- n ) string" ;
+( and bad ones )
+: extra ( a -- ) DUP ;
+: short ( a b -- c ) DROP DROP ;
+: uneven ( f -- n ) IF 1 THEN ;
+: keeps ( n -- ) >R ;
+`
+	f := New(nil, nil)
+	errs, err := f.CheckSource("test.fth", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := []string{
+		"extra ( a -- ) but its body does ( 1 -- 2 )",
+		"short ( a b -- c ) but its body does ( 2 -- 0 )",
+		"uneven ( f -- n ) but its body does ( 1 -- 0 | 1 )",
+		"keeps ( n -- ) but its body does ( 1 -- 0 R: 1 )",
+	}
+	if len(errs) != len(good) {
+		t.Fatalf("should find %d errors but found %v", len(good), errs)
+	}
+	for i, e := range errs {
+		if e.Error() != good[i] {
+			t.Errorf("should find %q but found %q", good[i], e.Error())
+		}
+	}
+	if errs := f.CheckEffects(); len(errs) != len(good)+1 {
+		t.Errorf("CheckEffects should find those and IS but found %v", errs)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hagna/eforth"
+)
+
+// keys the editor knows
+const (
+	keyCtrlA = 1
+	keyCtrlB = 2
+	keyCtrlC = 3
+	keyCtrlD = 4
+	keyCtrlE = 5
+	keyCtrlF = 6
+	keyCtrlH = 8
+	keyTab   = 9
+	keyLF    = 10
+	keyCtrlK = 11
+	keyCR    = 13
+	keyCtrlN = 14
+	keyCtrlP = 16
+	keyCtrlU = 21
+	keyCtrlW = 23
+	keyEsc   = 27
+	keyDel   = 127
+)
+
+// most lines of history kept
+const historySize = 500
+
+/*
+A line editor for a terminal in raw mode.  It moves the cursor, recalls the
+history kept in file and completes the names words returns.
+*/
+type editor struct {
+	in      <-chan byte
+	out     io.Writer
+	words   func() []string
+	history []string
+	file    string // history file or ""
+
+	line []byte
+	pos  int // of the cursor in line
+}
+
+// make an editor and read the history in file
+func newEditor(in <-chan byte, out io.Writer, words func() []string, file string) *editor {
+	e := &editor{in: in, out: out, words: words, file: file}
+	if fd, err := os.Open(file); err == nil {
+		s := bufio.NewScanner(fd)
+		for s.Scan() {
+			e.history = append(e.history, s.Text())
+		}
+		fd.Close()
+		if len(e.history) > historySize {
+			e.history = e.history[len(e.history)-historySize:]
+		}
+	}
+	return e
+}
+
+// return the next key or false at the end of the input
+func (e *editor) key() (byte, bool) {
+	b, ok := <-e.in
+	return b, ok
+}
+
+/*
+Edit a line and return it when return is pressed, the cursor is left at the
+end of it.  Returns io.EOF at the end of the input or for ^D on an empty
+line.
+*/
+func (e *editor) readLine() (string, error) {
+	e.line, e.pos = e.line[:0], 0
+	hist, draft := len(e.history), ""
+	recall := func(i int) {
+		if hist == len(e.history) {
+			draft = string(e.line)
+		}
+		hist = i
+		if i == len(e.history) {
+			e.line = append(e.line[:0], draft...)
+		} else {
+			e.line = append(e.line[:0], e.history[i]...)
+		}
+		e.pos = len(e.line)
+	}
+	for {
+		b, ok := e.key()
+		if !ok {
+			return "", io.EOF
+		}
+		switch b {
+		case keyCR, keyLF:
+			e.pos = len(e.line)
+			e.redraw()
+			return string(e.line), nil
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				return "", io.EOF
+			}
+			e.delete(e.pos, e.pos+1)
+		case keyCtrlC:
+			e.pos = len(e.line)
+			e.redraw()
+			io.WriteString(e.out, "^C\r\n")
+			e.line, e.pos = e.line[:0], 0
+			hist = len(e.history)
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlB:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyCtrlF:
+			if e.pos < len(e.line) {
+				e.pos++
+			}
+		case keyCtrlH, keyDel:
+			if e.pos > 0 {
+				e.delete(e.pos-1, e.pos)
+			}
+		case keyCtrlK:
+			e.delete(e.pos, len(e.line))
+		case keyCtrlU:
+			e.delete(0, e.pos)
+		case keyCtrlW:
+			i := e.pos
+			for i > 0 && e.line[i-1] == ' ' {
+				i--
+			}
+			for i > 0 && e.line[i-1] != ' ' {
+				i--
+			}
+			e.delete(i, e.pos)
+		case keyCtrlP:
+			if hist > 0 {
+				recall(hist - 1)
+			}
+		case keyCtrlN:
+			if hist < len(e.history) {
+				recall(hist + 1)
+			}
+		case keyTab:
+			e.complete()
+		case keyEsc:
+			switch e.escape() {
+			case 'A':
+				if hist > 0 {
+					recall(hist - 1)
+				}
+			case 'B':
+				if hist < len(e.history) {
+					recall(hist + 1)
+				}
+			case 'C':
+				if e.pos < len(e.line) {
+					e.pos++
+				}
+			case 'D':
+				if e.pos > 0 {
+					e.pos--
+				}
+			case 'H':
+				e.pos = 0
+			case 'F':
+				e.pos = len(e.line)
+			case '~':
+				e.delete(e.pos, e.pos+1)
+			}
+		default:
+			if b >= ' ' && b < keyDel {
+				e.insert(string(b))
+			}
+		}
+		e.redraw()
+	}
+}
+
+/*
+Read the rest of an escape sequence and return what it does: A, B, C and D
+for the arrows, H and F for home and end, ~ for delete or 0.
+*/
+func (e *editor) escape() byte {
+	b, _ := e.key()
+	if b != '[' && b != 'O' {
+		return 0
+	}
+	b, _ = e.key()
+	if b < '0' || b > '9' {
+		return b
+	}
+	n := b
+	for b >= '0' && b <= '9' {
+		b, _ = e.key()
+	}
+	switch {
+	case b != '~':
+		return 0
+	case n == '1' || n == '7':
+		return 'H'
+	case n == '4' || n == '8':
+		return 'F'
+	case n == '3':
+		return '~'
+	}
+	return 0
+}
+
+func (e *editor) insert(s string) {
+	e.line = append(e.line[:e.pos], append([]byte(s), e.line[e.pos:]...)...)
+	e.pos += len(s)
+}
+
+func (e *editor) delete(i, j int) {
+	if j > len(e.line) {
+		j = len(e.line)
+	}
+	if i >= j {
+		return
+	}
+	e.line = append(e.line[:i], e.line[j:]...)
+	e.pos = i
+}
+
+// write the line again and put the cursor where it is
+func (e *editor) redraw() {
+	s := "\r" + string(e.line) + "\x1b[K\r"
+	if e.pos > 0 {
+		s += fmt.Sprintf("\x1b[%dC", e.pos)
+	}
+	io.WriteString(e.out, s)
+}
+
+/*
+Complete the word before the cursor.  A name alone is completed with a
+space after it, several are completed as far as they agree and listed when
+they do not.
+*/
+func (e *editor) complete() {
+	i := e.pos
+	for i > 0 && e.line[i-1] != ' ' {
+		i--
+	}
+	prefix := string(e.line[i:e.pos])
+	var names []string
+	for _, w := range e.words() {
+		if strings.HasPrefix(w, prefix) {
+			names = append(names, w)
+		}
+	}
+	switch len(names) {
+	case 0:
+		io.WriteString(e.out, "\a")
+		return
+	case 1:
+		e.insert(names[0][len(prefix):] + " ")
+		return
+	}
+	sort.Strings(names)
+	common := names[0]
+	for _, w := range names[1:] {
+		for !strings.HasPrefix(w, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(prefix) {
+		e.insert(common[len(prefix):])
+		return
+	}
+	e.redraw()
+	io.WriteString(e.out, "\r\n"+strings.Join(names, " ")+"\r\n")
+}
+
+// remember line in the history and its file
+func (e *editor) remember(line string) {
+	if strings.TrimSpace(line) == "" ||
+		len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > historySize {
+		e.history = e.history[1:]
+	}
+	if e.file == "" {
+		return
+	}
+	if fd, err := os.OpenFile(e.file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err == nil {
+		fmt.Fprintln(fd, line)
+		fd.Close()
+	}
+}
+
+/*
+Give the keys typed while a line is interpreted to the word reading them,
+as a KeyReader so the keys typed ahead are left for the editor.
+*/
+func (e *editor) ReadKey() (byte, bool) {
+	select {
+	case b, ok := <-e.in:
+		return b, ok
+	default:
+		return 0, false
+	}
+}
+
+func (e *editor) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	b, ok := e.key()
+	if !ok {
+		return 0, io.EOF
+	}
+	p[0] = b
+	return 1, nil
+}
+
+/*
+Interpret the lines read with e until BYE or the end of the input.  The
+terminal is raw, set by raw, while a line is edited and cooked while it is
+interpreted, so KEY reads what is typed.  ^C while a line is interpreted
+ends eforth_repl, there is no way to stop just the word.
+*/
+func interact(f *eforth.Forth, e *editor, raw func(bool), quiet bool) {
+	f.Input = e
+	if quiet {
+		f.Quiet()
+	}
+	do := func(s string) error {
+		return f.Include("", strings.NewReader(s))
+	}
+	if do("'BOOT @EXECUTE") == eforth.ErrBye {
+		return
+	}
+	defer raw(false)
+	for {
+		raw(true)
+		line, err := e.readLine()
+		raw(false)
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			return
+		}
+		e.remember(line)
+		switch err := do(line); {
+		case err == eforth.ErrBye:
+			io.WriteString(e.out, "\r\n")
+			return
+		case err != nil:
+			// like QUIT, with the error in the line and the stack emptied
+			fmt.Fprintf(e.out, " %s \r\n", strings.TrimPrefix(err.Error(), ":1: "))
+			do("PRESET [")
+		default:
+			do(".OK")
+		}
+	}
+}
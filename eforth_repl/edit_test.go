@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hagna/eforth"
+)
+
+// return a channel holding the bytes of s and then closed
+func keys(s string) chan byte {
+	c := make(chan byte, len(s))
+	for _, b := range []byte(s) {
+		c <- b
+	}
+	close(c)
+	return c
+}
+
+func TestEditor(t *testing.T) {
+	words := func() []string { return []string{"DUP", "DROP", "DEPTH", "SWAP"} }
+	for _, v := range []struct{ keys, line string }{
+		{"abc\r", "abc"},
+		{"ac\x1b[DB\r", "aBc"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"abc\x7f\x7f\r", "a"},
+		{"abc\x02\x02\x0b\r", "a"},
+		{"abc def\x17\r", "abc "},
+		{"abc\x1b[D\x15\r", "c"},
+		{"ab\x1b[H\x1b[3~\r", "b"},
+		{"1 SW\t\r", "1 SWAP "},
+		{"1 DR\t.\r", "1 DROP ."},
+		{"D\tE\t\r", "DEPTH "},
+		{"xy\x03z\n", "z"},
+	} {
+		e := newEditor(keys(v.keys), new(bytes.Buffer), words, "")
+		line, err := e.readLine()
+		if err != nil || line != v.line {
+			t.Errorf("%q should edit to %q but was %q %v", v.keys, v.line, line, err)
+		}
+	}
+	o := new(bytes.Buffer)
+	e := newEditor(keys("D\t\r"), o, words, "")
+	e.readLine()
+	if !strings.Contains(o.String(), "DEPTH DROP DUP") {
+		t.Error("several names should be listed but printed", o.String())
+	}
+	if _, err := newEditor(keys("\x04"), o, words, "").readLine(); err == nil {
+		t.Error("^D on an empty line should end the input")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	e := newEditor(keys("one\rtwo\rtwo\r\x1b[A\x1b[A\x1b[A\x1b[B!\rthr\x10\x0e\x0eee\r"), new(bytes.Buffer), nil, file)
+	for _, want := range []string{"one", "two", "two", "two!", "three"} {
+		line, err := e.readLine()
+		if err != nil || line != want {
+			t.Fatalf("should read %q but read %q %v", want, line, err)
+		}
+		e.remember(line)
+	}
+	b, _ := ioutil.ReadFile(file)
+	if string(b) != "one\ntwo\ntwo!\nthree\n" {
+		t.Fatalf("the history file should hold the lines once but holds %q", b)
+	}
+	e = newEditor(keys("\x10\x10\r"), new(bytes.Buffer), nil, file)
+	if line, _ := e.readLine(); line != "two!" {
+		t.Error("the history should be read back but recalled", line)
+	}
+}
+
+func TestInteract(t *testing.T) {
+	o := new(bytes.Buffer)
+	f := eforth.New(nil, o)
+	in := ": sq DUP * ;\r7 sq .\rnosuch 1 2 3\rDEPTH .\rKEY .\rA BYE\r9 .\r"
+	e := newEditor(keys(in), o, f.Words, "")
+	modes := ""
+	interact(f, e, func(raw bool) {
+		if raw {
+			modes += "r"
+		} else {
+			modes += "c"
+		}
+	}, true)
+	for _, want := range []string{" 49\r\n", " nosuch ? \r\n", " 0\r\n", " 65\r\n"} {
+		if !strings.Contains(o.String(), want) {
+			t.Errorf("should print %q but printed %q", want, o.String())
+		}
+	}
+	if strings.Contains(o.String(), "ok") || strings.Contains(o.String(), " 9") {
+		t.Errorf("should be quiet and stop at BYE but printed %q", o.String())
+	}
+	if !strings.HasPrefix(modes, "rcrc") || !strings.HasSuffix(modes, "c") {
+		t.Error("the terminal should be raw only while editing but was", modes)
+	}
+}
@@ -1,11 +1,239 @@
 package main
 
 import (
-	"github.com/hagna/eforth"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hagna/eforth"
 )
 
+const usage = `usage: eforth_repl [flags] [file ...]
+
+Interpret the files and the -e expressions in order, then stop.  Without
+either the standard input is interpreted, interactively if it is a
+terminal.  Standard input that is not a terminal is interpreted like a
+file, without the sign on message and the ok prompts, and stops at the
+first error.  The exit status is 0, also after BYE, or 1 after an error.
+A session recorded with -record is replayed by running eforth_repl again
+with the same flags and -replay instead.
+
+`
+
 func main() {
-	f := eforth.New(os.Stdout, os.Stdin)
-	f.Main()
+	var o options
+	flag.Var((*strs)(&o.exprs), "e", "interpret `expr`, may be repeated")
+	flag.StringVar(&o.image, "image", "", "start from the image in `file`")
+	flag.StringVar(&o.save, "save", "", "save an image to `file` before stopping")
+	flag.StringVar(&o.record, "record", "", "record the session in `file` to replay it")
+	flag.StringVar(&o.replay, "replay", "", "replay the session recorded in `file`")
+	flag.StringVar(&o.cover, "cover", "", "write the coverage of the words defined to `file`, HTML if it ends in .html")
+	flag.BoolVar(&o.check, "check", false, "check the stack comments of the colon definitions in the files")
+	flag.BoolVar(&o.quiet, "q", false, "no sign on message and ok prompts on a terminal")
+	flag.BoolVar(&o.edit, "edit", true, "edit the lines typed on a terminal")
+	if home, err := os.UserHomeDir(); err == nil {
+		o.history = filepath.Join(home, ".eforth_history")
+	}
+	flag.StringVar(&o.history, "history", o.history, "keep the lines edited in `file`")
+	listen := flag.String("listen", "", "serve a Forth to each TCP connection on `addr`")
+	var lim limits
+	flag.DurationVar(&lim.Idle, "idle", 0, "end sessions idle this long")
+	flag.DurationVar(&lim.Lifetime, "lifetime", 0, "end sessions this long after they start")
+	flag.IntVar(&lim.Sessions, "sessions", 0, "most sessions at once")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *listen != "" {
+		l, err := net.Listen("tcp", *listen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("listening on", l.Addr())
+		log.Fatal(serve(l, lim))
+	}
+	o.files = flag.Args()
+	os.Exit(run(os.Stdin, os.Stdout, os.Stderr, o))
+}
+
+// what the flags ask run to do
+type options struct {
+	image, save string
+	record      string
+	replay      string
+	cover       string
+	check       bool
+	quiet, edit bool
+	history     string
+	files       []string
+	exprs       []string
+}
+
+// the -e flags in order
+type strs []string
+
+func (s *strs) String() string     { return strings.Join(*s, " ") }
+func (s *strs) Set(v string) error { *s = append(*s, v); return nil }
+
+// send the bytes read from r on the channel returned, closed at the end
+func readKeys(r io.Reader) <-chan byte {
+	c := make(chan byte, 64)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := r.Read(buf)
+			for _, b := range buf[:n] {
+				c <- b
+			}
+			if err != nil {
+				close(c)
+				return
+			}
+		}
+	}()
+	return c
+}
+
+/*
+Run a Forth the way the options say and return the exit status.  The files
+come before the expressions, as they usually define what the expressions
+use.
+*/
+func run(stdin *os.File, stdout, stderr io.Writer, o options) int {
+	f := eforth.New(stdin, stdout)
+	fail := func(err error) int {
+		if err == eforth.ErrBye {
+			return 0
+		}
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if o.image != "" {
+		fd, err := os.Open(o.image)
+		if err != nil {
+			return fail(err)
+		}
+		err = f.LoadImage(fd)
+		fd.Close()
+		if err != nil {
+			return fail(fmt.Errorf("%s: %v", o.image, err))
+		}
+	}
+	if o.cover != "" {
+		f.StartCoverage()
+		defer func() {
+			if err := writeCoverage(f, o.cover); err != nil {
+				fmt.Fprintln(stderr, err)
+			}
+		}()
+	}
+	if o.record != "" {
+		fd, err := os.Create(o.record)
+		if err != nil {
+			return fail(err)
+		}
+		defer fd.Close()
+		f.Record(fd)
+		defer f.Record(nil)
+	}
+	if o.replay != "" {
+		fd, err := os.Open(o.replay)
+		if err != nil {
+			return fail(err)
+		}
+		err = f.Replay(fd)
+		fd.Close()
+		if err != nil {
+			return fail(fmt.Errorf("%s: %v", o.replay, err))
+		}
+	}
+	status := 0
+	for _, file := range o.files {
+		if o.check {
+			errs, err := checkFile(f, file)
+			if err != nil {
+				return fail(err)
+			}
+			for _, e := range errs {
+				fmt.Fprintf(stderr, "%s: %v\n", file, e)
+				status = 1
+			}
+		} else if err := f.LoadFile(file); err != nil {
+			return fail(err)
+		}
+	}
+	for _, e := range o.exprs {
+		if err := f.Include("-e", strings.NewReader(e)); err != nil {
+			return fail(err)
+		}
+	}
+	if len(o.files) == 0 && len(o.exprs) == 0 {
+		st, err := stdin.Stat()
+		if o.record != "" || o.replay != "" {
+			// only Main reads the keys the way they are recorded
+			if o.quiet {
+				f.Quiet()
+			}
+			f.Main()
+		} else if err != nil || st.Mode()&os.ModeCharDevice == 0 {
+			if err := f.Include("-", stdin); err != nil {
+				return fail(err)
+			}
+		} else if raw, err := terminal(stdin.Fd()); o.edit && err == nil {
+			e := newEditor(readKeys(stdin), stdout, f.Words, o.history)
+			interact(f, e, raw, o.quiet)
+		} else {
+			if o.quiet {
+				f.Quiet()
+			}
+			f.Main()
+		}
+	}
+	if o.save != "" {
+		fd, err := os.Create(o.save)
+		if err != nil {
+			return fail(err)
+		}
+		err = f.SaveImage(fd)
+		if e := fd.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return status
+}
+
+// load file into f checking the stack comments of its colon definitions
+func checkFile(f *eforth.Forth, file string) ([]eforth.EffectError, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return f.CheckSource(file, fd)
+}
+
+// write the coverage of f to file
+func writeCoverage(f *eforth.Forth, file string) error {
+	fd, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(file, ".html") {
+		err = f.WriteCoverageHTML(fd)
+	} else {
+		err = f.WriteCoverage(fd)
+	}
+	if e := fd.Close(); err == nil {
+		err = e
+	}
+	return err
 }
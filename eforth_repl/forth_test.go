@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// run with stdin read from a file holding in
+func runWith(t *testing.T, in, image, save string, files, exprs []string) (int, string, string) {
+	p := filepath.Join(t.TempDir(), "stdin")
+	if err := ioutil.WriteFile(p, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	o, e := new(bytes.Buffer), new(bytes.Buffer)
+	status := run(stdin, o, e, options{image: image, save: save, files: files, exprs: exprs})
+	return status, o.String(), e.String()
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.fth")
+	ioutil.WriteFile(lib, []byte(": sq DUP * ;\n"), 0644)
+	img := filepath.Join(dir, "lib.img")
+	for _, v := range []struct {
+		in, image, save string
+		files, exprs    []string
+		status          int
+		out, err        string
+	}{
+		{in: "2 3 + .\n4 .", out: " 5 4"},
+		{in: "1 . BYE 2 .", out: " 1"},
+		{in: "1 .\nnosuch 2 .", out: " 1", status: 1, err: "-:2: nosuch ?\n"},
+		{exprs: []string{"7 sq .", "2 sq ."}, files: []string{lib}, out: " 49 4"},
+		{exprs: []string{"sq"}, status: 1, err: "-e:1: sq ?\n"},
+		{files: []string{filepath.Join(dir, "none")}, status: 1},
+		{files: []string{lib}, save: img},
+		{in: "5 sq .", image: img, out: " 25"},
+		{image: lib, status: 1, err: lib + ": not an eForth image\n"},
+	} {
+		status, out, err := runWith(t, v.in, v.image, v.save, v.files, v.exprs)
+		if status != v.status || out != v.out || v.err != "" && err != v.err {
+			t.Errorf("%+v returned %d printing %q and %q", v, status, out, err)
+		}
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	rec := filepath.Join(t.TempDir(), "rec")
+	p := filepath.Join(t.TempDir(), "stdin")
+	ioutil.WriteFile(p, []byte("TICKS . 1 2 + .\nBYE\n"), 0644)
+	var outs [2]string
+	for i, o := range []options{{record: rec, quiet: true}, {replay: rec, quiet: true}} {
+		stdin, err := os.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, e := new(bytes.Buffer), new(bytes.Buffer)
+		if status := run(stdin, out, e, o); status != 0 {
+			t.Fatal(o, "returned", status, e)
+		}
+		stdin.Close()
+		outs[i] = out.String()
+	}
+	if outs[0] == "" || outs[1] != outs[0] {
+		t.Errorf("printed %q, the replay printed %q", outs[0], outs[1])
+	}
+}
+
+func TestCover(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.fth")
+	ioutil.WriteFile(lib, []byte(": sq DUP * ;\n: cube DUP sq * ;\n"), 0644)
+	for _, name := range []string{"cover.txt", "cover.html"} {
+		p := filepath.Join(dir, name)
+		o := options{cover: p, files: []string{lib}, exprs: []string{"3 sq . BYE"}}
+		if status := run(nil, new(bytes.Buffer), new(bytes.Buffer), o); status != 0 {
+			t.Fatal(name, "returned", status)
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(b, []byte("sq")) || !bytes.Contains(b, []byte("cube")) {
+			t.Errorf("%s should cover sq and cube but is\n%s", name, b)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	lib := filepath.Join(t.TempDir(), "lib.fth")
+	ioutil.WriteFile(lib, []byte(": sq ( n -- n ) DUP * ;\n: bad ( n -- ) sq ;\n"), 0644)
+	o, e := new(bytes.Buffer), new(bytes.Buffer)
+	status := run(nil, o, e, options{check: true, files: []string{lib}, exprs: []string{"3 sq ."}})
+	if status != 1 || o.String() != " 9" || e.String() != lib+": bad ( n -- ) but its body does ( 1 -- 1 )\n" {
+		t.Errorf("returned %d printing %q and %q", status, o, e)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hagna/eforth"
+)
+
+// telnet commands
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetDONT = 254
+	telnetIAC  = 255
+	telnetECHO = 1
+	telnetSGA  = 3
+)
+
+/*
+Limits on the sessions of a server.  Idle is how long a session may wait
+for input or for the client to read output, Lifetime is how long a session
+may last and Sessions is how many may run at once.  A zero means no limit.
+*/
+type limits struct {
+	Idle     time.Duration
+	Lifetime time.Duration
+	Sessions int
+}
+
+/*
+Accept connections on l and give each its own Forth until l is closed.
+*/
+func serve(l net.Listener, lim limits) error {
+	var sem chan bool
+	if lim.Sessions > 0 {
+		sem = make(chan bool, lim.Sessions)
+	}
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		if sem != nil {
+			select {
+			case sem <- true:
+			default:
+				io.WriteString(c, "too many sessions\r\n")
+				c.Close()
+				continue
+			}
+		}
+		go func() {
+			session(c, lim)
+			if sem != nil {
+				<-sem
+			}
+		}()
+	}
+}
+
+/*
+Run a Forth on the connection c until BYE, the client hangs up or a limit
+is reached.  The Lifetime and a hang up also end a word that runs without
+reading input.
+*/
+func session(c net.Conn, lim limits) {
+	defer c.Close()
+	log.Println(c.RemoteAddr(), "connected")
+	s := &telnetConn{c: c, lim: lim, ready: make(chan bool, 1)}
+	f := eforth.New(s, s)
+	if lim.Lifetime > 0 {
+		s.end = time.Now().Add(lim.Lifetime)
+		t := time.AfterFunc(lim.Lifetime, func() {
+			f.Stop()
+			c.SetWriteDeadline(time.Now().Add(time.Second)) // past the end
+			io.WriteString(c, "\r\ntimeout\r\n")
+		})
+		defer t.Stop()
+	}
+	go s.pump(f.Stop)
+	// the client leaves the echo to eForth and sends every key
+	c.Write([]byte{telnetIAC, telnetWILL, telnetECHO, telnetIAC, telnetWILL, telnetSGA})
+	f.Main()
+	log.Println(c.RemoteAddr(), "disconnected")
+}
+
+/*
+A connection that speaks enough telnet for a line at a time to reach eForth
+the way a terminal sends it.  Commands are dropped, CR LF, CR NUL and LF
+become one CR and DEL becomes backspace.  When the client hangs up or a
+limit is reached the reader ends the session with BYE.
+*/
+type telnetConn struct {
+	c       net.Conn
+	lim     limits
+	end     time.Time // end of the session or zero
+	state   int       // of the telnet command being read
+	cr      bool      // the last byte was a CR
+	bye     []byte    // left to read of the closing BYE
+	err     error
+	pending []byte
+
+	mu     sync.Mutex
+	queue  []byte    // read by pump and not yet by Read
+	closed error     // why pump stopped, nil while it reads
+	ready  chan bool // tells Read there is more in queue or pump stopped
+}
+
+// the deadline for the next write
+func (t *telnetConn) deadline() time.Time {
+	var d time.Time
+	if t.lim.Idle > 0 {
+		d = time.Now().Add(t.lim.Idle)
+	}
+	if !t.end.IsZero() && (d.IsZero() || t.end.Before(d)) {
+		d = t.end
+	}
+	return d
+}
+
+/*
+Read the connection ahead of eForth into the queue, so a client that hangs
+up is seen at once, also while eForth runs a word.  Then call hangup.
+*/
+func (t *telnetConn) pump(hangup func()) {
+	buf := make([]byte, 512)
+	for {
+		n, err := t.c.Read(buf)
+		t.mu.Lock()
+		t.queue = append(t.queue, buf[:n]...)
+		t.closed = err
+		t.mu.Unlock()
+		select {
+		case t.ready <- true:
+		default:
+		}
+		if err != nil {
+			hangup()
+			return
+		}
+	}
+}
+
+func (t *telnetConn) Read(p []byte) (int, error) {
+	for {
+		if len(t.bye) > 0 {
+			n := copy(p, t.bye)
+			t.bye = t.bye[n:]
+			return n, nil
+		}
+		if t.err != nil {
+			return 0, t.err
+		}
+		if len(t.pending) == 0 {
+			t.mu.Lock()
+			t.pending, t.queue = t.queue, nil
+			closed := t.closed
+			t.mu.Unlock()
+			if len(t.pending) == 0 && closed == nil && !t.wait() {
+				io.WriteString(t, "\r\ntimeout\r\n")
+				closed = io.EOF
+			}
+			if len(t.pending) == 0 && closed != nil {
+				// leave compiling too, the text interpreter reads a line at a time
+				t.bye, t.err = []byte("\r[ BYE\r"), io.EOF
+				continue
+			}
+		}
+		n := 0
+		for len(t.pending) > 0 && n < len(p) {
+			b := t.pending[0]
+			t.pending = t.pending[1:]
+			if b, ok := t.filter(b); ok {
+				p[n] = b
+				n++
+			}
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// wait for pump to read more, return false if the client was idle too long,
+// the Lifetime is ended by session
+func (t *telnetConn) wait() bool {
+	if t.lim.Idle == 0 {
+		<-t.ready
+		return true
+	}
+	timer := time.NewTimer(t.lim.Idle)
+	defer timer.Stop()
+	select {
+	case <-t.ready:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// return the byte eForth reads for b and whether there is one
+func (t *telnetConn) filter(b byte) (byte, bool) {
+	switch t.state {
+	case telnetIAC:
+		switch {
+		case b == telnetIAC:
+			t.state = 0
+			return b, true
+		case b == telnetSB:
+			t.state = telnetSB
+		case b >= telnetWILL && b <= telnetDONT:
+			t.state = telnetWILL
+		default:
+			t.state = 0
+		}
+		return 0, false
+	case telnetWILL: // the option
+		t.state = 0
+		return 0, false
+	case telnetSB: // skip to IAC SE
+		if b == telnetIAC {
+			t.state = telnetSE
+		}
+		return 0, false
+	case telnetSE:
+		if b == telnetSE {
+			t.state = 0
+		} else {
+			t.state = telnetSB
+		}
+		return 0, false
+	}
+	if b == telnetIAC {
+		t.state = telnetIAC
+		return 0, false
+	}
+	cr := t.cr
+	t.cr = b == 13
+	switch {
+	case cr && (b == 10 || b == 0):
+		return 0, false
+	case b == 10:
+		return 13, true
+	case b == 127:
+		return 8, true
+	}
+	return b, true
+}
+
+// Write p doubling IAC, a client that stops reading ends the session
+func (t *telnetConn) Write(p []byte) (int, error) {
+	q := p
+	for i, b := range p {
+		if b == telnetIAC {
+			q = append([]byte(nil), p[:i]...)
+			for _, b := range p[i:] {
+				if b == telnetIAC {
+					q = append(q, telnetIAC)
+				}
+				q = append(q, b)
+			}
+			break
+		}
+	}
+	t.c.SetWriteDeadline(t.deadline())
+	if _, err := t.c.Write(q); err != nil {
+		t.c.Close() // so the reader ends the session
+		return 0, err
+	}
+	return len(p), nil
+}
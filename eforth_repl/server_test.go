@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// start a server on localhost and return its address
+func startServer(t *testing.T, lim limits) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serve(l, lim)
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// read from c until s arrives and return what was read
+func readUntil(t *testing.T, c net.Conn, s string) string {
+	c.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var got []byte
+	buf := make([]byte, 256)
+	for !bytes.Contains(got, []byte(s)) {
+		n, err := c.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil {
+			t.Fatalf("waiting for %q got %q and %v", s, got, err)
+		}
+	}
+	return string(got)
+}
+
+// read from c until the server closes it
+func readAll(t *testing.T, c net.Conn) string {
+	c.SetReadDeadline(time.Now().Add(10 * time.Second))
+	got, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatalf("the session should end but got %q and %v", got, err)
+	}
+	return string(got)
+}
+
+func TestSession(t *testing.T) {
+	addr, stop := startServer(t, limits{})
+	defer stop()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	readUntil(t, c, "eForth")
+	io.WriteString(c, "2 3 + .\r\n")
+	readUntil(t, c, " 5 ok")
+	// telnet sends CR NUL for return and IAC commands between
+	c.Write([]byte("4 \xff\xfd\x01.\r\x00BYE\r\n"))
+	if got := readAll(t, c); !strings.Contains(got, " 4 ok") || strings.Count(got, "ok") != 1 {
+		t.Errorf("should print 4 and one ok but printed %q", got)
+	}
+}
+
+func TestSessionsIndependent(t *testing.T) {
+	addr, stop := startServer(t, limits{})
+	defer stop()
+	a, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	io.WriteString(a, ": sq DUP * ; 7 sq .\r\n")
+	readUntil(t, a, " 49 ok")
+	io.WriteString(b, "7 sq .\r\n")
+	readUntil(t, b, "sq ?")
+}
+
+func TestIdle(t *testing.T) {
+	addr, stop := startServer(t, limits{Idle: 100 * time.Millisecond})
+	defer stop()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// an unfinished definition is left too
+	io.WriteString(c, ": half 2 /")
+	if got := readAll(t, c); !strings.Contains(got, "timeout") {
+		t.Errorf("should time out but printed %q", got)
+	}
+}
+
+func TestTooManySessions(t *testing.T) {
+	addr, stop := startServer(t, limits{Sessions: 1})
+	defer stop()
+	a, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	readUntil(t, a, "eForth")
+	b, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if got := readAll(t, b); got != "too many sessions\r\n" {
+		t.Errorf("the second session should be refused but got %q", got)
+	}
+	io.WriteString(a, "BYE\r\n")
+	readAll(t, a)
+	time.Sleep(100 * time.Millisecond) // for the server to see the end
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	readUntil(t, c, "eForth")
+}
+
+// dial addr until a session starts, the slot of one that ended may take a
+// moment to be freed
+func dialSession(t *testing.T, addr string) net.Conn {
+	for i := 0; i < 50; i++ {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetReadDeadline(time.Now().Add(10 * time.Second))
+		buf := make([]byte, 256)
+		n, _ := c.Read(buf)
+		if !strings.Contains(string(buf[:n]), "too many sessions") {
+			return c
+		}
+		c.Close()
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("the slot of the session that ended was not freed")
+	return nil
+}
+
+// a word that runs forever ends at the Lifetime or when the client hangs up
+func TestComputeBound(t *testing.T) {
+	addr, stop := startServer(t, limits{Lifetime: 200 * time.Millisecond, Sessions: 1})
+	defer stop()
+	c := dialSession(t, addr)
+	io.WriteString(c, ": x BEGIN AGAIN ; x\r\n")
+	if got := readAll(t, c); !strings.Contains(got, "timeout") {
+		t.Errorf("should time out but printed %q", got)
+	}
+	c.Close()
+	dialSession(t, addr).Close()
+
+	addr, stop = startServer(t, limits{Sessions: 1})
+	defer stop()
+	c = dialSession(t, addr)
+	io.WriteString(c, ": x BEGIN AGAIN ; x\r\n")
+	time.Sleep(50 * time.Millisecond)
+	c.Close()
+	dialSession(t, addr).Close()
+}
+
+func TestTelnetFilter(t *testing.T) {
+	for _, v := range []struct{ in, out string }{
+		{"a\r\nb\r\x00c\nd\r", "a\rb\rc\rd\r"},
+		{"\xff\xfb\x18x\xff\xfa\x18\x00VT\xff\xf0y", "xy"},
+		{"\xff\xff\xff\xf1z", "\xffz"},
+		{"ab\x7fc", "ab\bc"},
+	} {
+		var got []byte
+		tc := &telnetConn{}
+		for _, b := range []byte(v.in) {
+			if b, ok := tc.filter(b); ok {
+				got = append(got, b)
+			}
+		}
+		if string(got) != v.out {
+			t.Errorf("%q should read as %q not %q", v.in, v.out, got)
+		}
+	}
+}
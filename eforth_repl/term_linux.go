@@ -0,0 +1,36 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+/*
+Return a function putting the terminal on fd in raw mode or back in the
+mode it is in now.
+*/
+func terminal(fd uintptr) (func(bool), error) {
+	var cooked syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, &cooked); err != nil {
+		return nil, err
+	}
+	raw := cooked
+	raw.Iflag &^= syscall.ICRNL | syscall.INLCR | syscall.IGNCR | syscall.IXON
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	return func(on bool) {
+		if on {
+			ioctl(fd, syscall.TCSETS, &raw)
+		} else {
+			ioctl(fd, syscall.TCSETS, &cooked)
+		}
+	}, nil
+}
+
+func ioctl(fd, req uintptr, t *syscall.Termios) error {
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t))); e != 0 {
+		return e
+	}
+	return nil
+}
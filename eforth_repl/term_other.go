@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+)
+
+// there is no raw mode here, the terminal stays cooked
+func terminal(fd uintptr) (func(bool), error) {
+	return nil, errors.New("no raw terminal mode")
+}
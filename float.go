@@ -0,0 +1,686 @@
+package eforth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	FLOATT  = 8  // size of a float
+	FSTACKK = 16 // depth of the float stack
+)
+
+func (f *Forth) addFloat() {
+	f.fsp0, _ = f.allot(FSTACKK * FLOATT)
+	f.fsp0 += FSTACKK * FLOATT
+	f.FP = f.fsp0
+	f.precision = 15
+
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"doFLIT", "DOFLIT", f.doFLIT, COMPO},
+		{"F,", "FCOMMA", f._Fcomma, 0},
+		{"F@", "FAT", f._Fat, 0},
+		{"F!", "FSTOR", f._Fstore, 0},
+		{"SF@", "SFAT", f._SFat, 0},
+		{"SF!", "SFSTO", f._SFstore, 0},
+		{"FDEPTH", "FDEPT", f._Fdepth, 0},
+		{"fclear", "FCLEA", f._Fclear, 0},
+		{"FDROP", "FDROP", f.fstack(1, func(r []float64) []float64 { return nil }), 0},
+		{"FDUP", "FDUPP", f.fstack(1, func(r []float64) []float64 { return []float64{r[0], r[0]} }), 0},
+		{"FSWAP", "FSWAP", f.fstack(2, func(r []float64) []float64 { return []float64{r[1], r[0]} }), 0},
+		{"FOVER", "FOVER", f.fstack(2, func(r []float64) []float64 { return []float64{r[0], r[1], r[0]} }), 0},
+		{"FROT", "FROT", f.fstack(3, func(r []float64) []float64 { return []float64{r[1], r[2], r[0]} }), 0},
+		{"F+", "FPLUS", f.fbinary(func(a, b float64) float64 { return a + b }), 0},
+		{"F-", "FSUBB", f.fbinary(func(a, b float64) float64 { return a - b }), 0},
+		{"F*", "FSTAR", f.fbinary(func(a, b float64) float64 { return a * b }), 0},
+		{"F/", "FSLAS", f.fbinary(func(a, b float64) float64 { return a / b }), 0},
+		{"F**", "FPOWE", f.fbinary(math.Pow), 0},
+		{"FMAX", "FMAX", f.fbinary(math.Max), 0},
+		{"FMIN", "FMIN", f.fbinary(math.Min), 0},
+		{"FATAN2", "FATN2", f.fbinary(math.Atan2), 0},
+		{"FNEGATE", "FNEGA", f.funary(func(a float64) float64 { return -a }), 0},
+		{"FABS", "FABS", f.funary(math.Abs), 0},
+		{"FLOOR", "FLOOR", f.funary(math.Floor), 0},
+		{"FROUND", "FROUN", f.funary(math.RoundToEven), 0},
+		{"FTRUNC", "FTRUN", f.funary(math.Trunc), 0},
+		{"FSQRT", "FSQRT", f.funary(math.Sqrt), 0},
+		{"FEXP", "FEXP", f.funary(math.Exp), 0},
+		{"FEXPM1", "FEXPM", f.funary(math.Expm1), 0},
+		{"FLN", "FLN", f.funary(math.Log), 0},
+		{"FLNP1", "FLNP1", f.funary(math.Log1p), 0},
+		{"FLOG", "FLOG", f.funary(math.Log10), 0},
+		{"FALOG", "FALOG", f.funary(func(a float64) float64 { return math.Pow(10, a) }), 0},
+		{"FSIN", "FSIN", f.funary(math.Sin), 0},
+		{"FCOS", "FCOS", f.funary(math.Cos), 0},
+		{"FTAN", "FTAN", f.funary(math.Tan), 0},
+		{"FASIN", "FASIN", f.funary(math.Asin), 0},
+		{"FACOS", "FACOS", f.funary(math.Acos), 0},
+		{"FATAN", "FATAN", f.funary(math.Atan), 0},
+		{"FSINH", "FSINH", f.funary(math.Sinh), 0},
+		{"FCOSH", "FCOSH", f.funary(math.Cosh), 0},
+		{"FTANH", "FTANH", f.funary(math.Tanh), 0},
+		{"FASINH", "FASNH", f.funary(math.Asinh), 0},
+		{"FACOSH", "FACSH", f.funary(math.Acosh), 0},
+		{"FATANH", "FATNH", f.funary(math.Atanh), 0},
+		{"FSINCOS", "FSICO", f.fstack(1, func(r []float64) []float64 { return []float64{math.Sin(r[0]), math.Cos(r[0])} }), 0},
+		{"F0<", "FZLES", f.fcompare(1, func(r []float64) bool { return r[0] < 0 }), 0},
+		{"F0=", "FZEQU", f.fcompare(1, func(r []float64) bool { return r[0] == 0 }), 0},
+		{"F<", "FLESS", f.fcompare(2, func(r []float64) bool { return r[0] < r[1] }), 0},
+		{"F~", "FPROX", f.fcompare(3, fproximate), 0},
+		{"D>F", "DTOF", f._DtoF, 0},
+		{"F>D", "FTOD", f._FtoD, 0},
+		{"S>F", "STOF", f._StoF, 0},
+		{"F>S", "FTOS", f._FtoS, 0},
+		{">FLOAT", "TOFLT", f._ToFloat, 0},
+		{"(fnumber)", "FNUMB", f._Fnumber, 0},
+		{"REPRESENT", "REPRE", f._Represent, 0},
+		{"fstr", "FSTR", f.fformat('f'), 0},
+		{"fsstr", "FSSTR", f.fformat('s'), 0},
+		{"festr", "FESTR", f.fformat('e'), 0},
+		{"PRECISION", "PRECI", f._Precision, 0},
+		{"SET-PRECISION", "SPREC", f._SetPrecision, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Floating point
+
+;   FLOAT+	( a -- a )
+;		Add the size of a float to address.
+
+		$COLON	6,'FLOAT+',FLOTP
+		DW	DOLIT,8,PLUS,EXIT
+
+;   FLOATS	( n -- n )
+;		Multiply tos by the size of a float.
+
+		$COLON	6,'FLOATS',FLOTS
+		DW	DOLIT,8,STAR,EXIT
+
+;   FALIGNED	( a -- a )
+;		Align address for a float.
+
+		$COLON	8,'FALIGNED',FALGD
+		DW	ALGND,EXIT
+
+;   FALIGN	( -- )
+;		Align the code dictionary for a float.
+
+		$COLON	6,'FALIGN',FALGN
+		DW	HERE,ALGND,CP,STORE,EXIT
+
+;   DF@		( a -- ; F: -- r )
+;		Fetch a double precision float.
+
+		$COLON	3,'DF@',DFAT
+		DW	FAT,EXIT
+
+;   DF!		( a -- ; F: r -- )
+;		Store a double precision float.
+
+		$COLON	3,'DF!',DFSTO
+		DW	FSTOR,EXIT
+
+;   DFLOAT+	( a -- a )
+;		Add the size of a double precision float to address.
+
+		$COLON	7,'DFLOAT+',DFLTP
+		DW	FLOTP,EXIT
+
+;   DFLOATS	( n -- n )
+;		Multiply tos by the size of a double precision float.
+
+		$COLON	7,'DFLOATS',DFLTS
+		DW	FLOTS,EXIT
+
+;   DFALIGNED	( a -- a )
+;		Align address for a double precision float.
+
+		$COLON	9,'DFALIGNED',DFALD
+		DW	ALGND,EXIT
+
+;   DFALIGN	( -- )
+;		Align the code dictionary for a double precision float.
+
+		$COLON	7,'DFALIGN',DFALN
+		DW	FALGN,EXIT
+
+;   SFLOAT+	( a -- a )
+;		Add the size of a single precision float to address.
+
+		$COLON	7,'SFLOAT+',SFLTP
+		DW	DOLIT,4,PLUS,EXIT
+
+;   SFLOATS	( n -- n )
+;		Multiply tos by the size of a single precision float.
+
+		$COLON	7,'SFLOATS',SFLTS
+		DW	DOLIT,4,STAR,EXIT
+
+;   SFALIGNED	( a -- a )
+;		Align address for a single precision float.
+
+		$COLON	9,'SFALIGNED',SFALD
+		DW	ALGND,EXIT
+
+;   SFALIGN	( -- )
+;		Align the code dictionary for a single precision float.
+
+		$COLON	7,'SFALIGN',SFALN
+		DW	FALGN,EXIT
+
+;   F.		( -- ; F: r -- )
+;		Display a float in fixed point notation, preceeded by a space.
+
+		$COLON	2,'F.',FDOT
+		DW	FSTR,SPACE,TYPEE,EXIT
+
+;   FS.		( -- ; F: r -- )
+;		Display a float in scientific notation, preceeded by a space.
+
+		$COLON	3,'FS.',FSDOT
+		DW	FSSTR,SPACE,TYPEE,EXIT
+
+;   FE.		( -- ; F: r -- )
+;		Display a float in engineering notation, preceeded by a space.
+
+		$COLON	3,'FE.',FEDOT
+		DW	FESTR,SPACE,TYPEE,EXIT
+
+;   FLITERAL	( -- ; F: r -- )
+;		Compile a float literal.
+
+		$COLON	IMEDD+8,'FLITERAL',FLITE
+		DW	COMPI,DOFLIT,FCOMMA,EXIT
+
+;   doFCON	( -- ; F: -- r )
+;		Run time routine for FCONSTANT.
+
+		$COLON	COMPO+6,'doFCON',DOFCON
+		DW	RFROM,FAT,EXIT
+
+;   FCONSTANT	( -- ; <string> F: r -- )
+;		Compile a new float constant.
+
+		$COLON	9,'FCONSTANT',FCONS
+		DW	TOKEN,SNAME,OVERT
+		DW	DOLIT,DOLST,CALLC
+		DW	COMPI,DOFCON,FCOMMA,EXIT
+
+;   FVARIABLE	( -- ; <string> )
+;		Compile a new float variable.
+
+		$COLON	9,'FVARIABLE',FVARI
+		DW	CREAT,DOLIT,8,ALLOT,EXIT
+
+;   NUMBER?	( a -- n T | d T | T | a F ; F: -- r )
+;		Convert a number string to integer, double or float. Push a flag on tos.
+
+		$COLON	7,'NUMBER?',FNUMQ
+		DW	DNUMQ,QDUP		;?integer or double
+		DW	QBRAN,FNUM1
+		DW	EXIT
+FNUM1:		DW	DUPP,COUNT,FNUMB,DUPP	;?float
+		DW	QBRAN,FNUM2
+		DW	SWAP,DROP
+FNUM2:		DW	EXIT
+
+;   PRESET	( -- )
+;		Reset data and float stack pointers and the terminal input buffer.
+
+		$COLON	6,'PRESET',FPRES
+		DW	PRESE,FCLEA,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+	f.callLatest("QUIT", "PRESET")
+	f.callLatest("COLD", "PRESET")
+}
+
+/*
+Push r onto the float stack, unless it is full.
+*/
+func (f *Forth) FPush(r float64) error {
+	if f.FDepth() >= FSTACKK {
+		return errors.New("float stack overflow")
+	}
+	f.FP = f.FP - FLOATT
+	f.setFloat(f.FP, r)
+	return nil
+}
+
+/*
+Pop off of the float stack.
+*/
+func (f *Forth) FPop() float64 {
+	r := f.float(f.FP)
+	f.FP = f.FP + FLOATT
+	return r
+}
+
+/*
+Return the number of floats on the float stack.
+*/
+func (f *Forth) FDepth() int {
+	return int(f.fsp0-f.FP) / FLOATT
+}
+
+func (f *Forth) float(a uint16) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(f.Memory[a:]))
+}
+
+func (f *Forth) setFloat(a uint16, r float64) {
+	binary.LittleEndian.PutUint64(f.Memory[a:], math.Float64bits(r))
+}
+
+/*
+Return a primitive that pops in floats, runs op on them (deepest first) and
+pushes the results.  Throws if the float stack would under or overflow.
+*/
+func (f *Forth) fstack(in int, op func(r []float64) []float64) fn {
+	return func() {
+		if f.FDepth() < in {
+			f.throwMessage("float stack underflow")
+			return
+		}
+		r := make([]float64, in)
+		for i := in - 1; i >= 0; i-- {
+			r[i] = f.FPop()
+		}
+		res := op(r)
+		if f.FDepth()+len(res) > FSTACKK {
+			f.throwMessage("float stack overflow")
+			return
+		}
+		for _, v := range res {
+			f.FPush(v)
+		}
+		f.Next()
+	}
+}
+
+func (f *Forth) funary(op func(a float64) float64) fn {
+	return f.fstack(1, func(r []float64) []float64 { return []float64{op(r[0])} })
+}
+
+func (f *Forth) fbinary(op func(a, b float64) float64) fn {
+	return f.fstack(2, func(r []float64) []float64 { return []float64{op(r[0], r[1])} })
+}
+
+// return a primitive that pops in floats and pushes the flag cmp returns
+func (f *Forth) fcompare(in int, cmp func(r []float64) bool) fn {
+	return f.fstack(in, func(r []float64) []float64 {
+		if cmp(r) {
+			f.Push(asuint16(-1))
+		} else {
+			f.Push(0)
+		}
+		return nil
+	})
+}
+
+// F~ ( -- t ; F: r1 r2 r3 -- )
+func fproximate(r []float64) bool {
+	r1, r2, r3 := r[0], r[1], r[2]
+	switch {
+	case r3 > 0:
+		return math.Abs(r1-r2) < r3
+	case r3 == 0:
+		return math.Float64bits(r1) == math.Float64bits(r2)
+	}
+	return math.Abs(r1-r2) < -r3*(math.Abs(r1)+math.Abs(r2))
+}
+
+// push r or throw if the float stack is full, returns false if it threw
+func (f *Forth) fpush(r float64) bool {
+	if err := f.FPush(r); err != nil {
+		f.throwMessage(err.Error())
+		return false
+	}
+	return true
+}
+
+// pop a float or throw if the float stack is empty, returns false if it threw
+func (f *Forth) fpop() (float64, bool) {
+	if f.FDepth() < 1 {
+		f.throwMessage("float stack underflow")
+		return 0, false
+	}
+	return f.FPop(), true
+}
+
+// doFLIT ( -- ; F: -- r ) push the inline float literal on the float stack
+func (f *Forth) doFLIT() {
+	if !f.inMemory(f.IP, FLOATT) {
+		return
+	}
+	r := f.float(f.IP)
+	f.IP += FLOATT
+	if f.fpush(r) {
+		f.Next()
+	}
+}
+
+// F, ( -- ; F: r -- ) compile a float into the code dictionary
+func (f *Forth) _Fcomma() {
+	if r, ok := f.fpop(); ok {
+		cp := f.userAddr("CP")
+		if !f.inMemory(f.WordPtr(cp), FLOATT) {
+			return
+		}
+		f.setFloat(f.WordPtr(cp), r)
+		f.SetWordPtr(cp, f.WordPtr(cp)+FLOATT)
+		f.Next()
+	}
+}
+
+// F@ ( a -- ; F: -- r ) fetch a float
+func (f *Forth) _Fat() {
+	a := f.Pop()
+	if f.inMemory(a, FLOATT) && f.fpush(f.float(a)) {
+		f.Next()
+	}
+}
+
+// F! ( a -- ; F: r -- ) store a float
+func (f *Forth) _Fstore() {
+	a := f.Pop()
+	if !f.inMemory(a, FLOATT) {
+		return
+	}
+	if r, ok := f.fpop(); ok {
+		f.setFloat(a, r)
+		f.Next()
+	}
+}
+
+// SF@ ( a -- ; F: -- r ) fetch a single precision float
+func (f *Forth) _SFat() {
+	a := f.Pop()
+	if !f.inMemory(a, 4) {
+		return
+	}
+	r := math.Float32frombits(binary.LittleEndian.Uint32(f.Memory[a:]))
+	if f.fpush(float64(r)) {
+		f.Next()
+	}
+}
+
+// SF! ( a -- ; F: r -- ) store a single precision float
+func (f *Forth) _SFstore() {
+	a := f.Pop()
+	if !f.inMemory(a, 4) {
+		return
+	}
+	if r, ok := f.fpop(); ok {
+		binary.LittleEndian.PutUint32(f.Memory[a:], math.Float32bits(float32(r)))
+		f.Next()
+	}
+}
+
+// FDEPTH ( -- n ) return the depth of the float stack
+func (f *Forth) _Fdepth() {
+	f.Push(uint16(f.FDepth()))
+	f.Next()
+}
+
+// fclear ( F: ... -- ) empty the float stack
+func (f *Forth) _Fclear() {
+	f.FP = f.fsp0
+	f.Next()
+}
+
+// D>F ( d -- ; F: -- r )
+func (f *Forth) _DtoF() {
+	if f.fpush(float64(f.popDouble())) {
+		f.Next()
+	}
+}
+
+// F>D ( -- d ; F: r -- ) truncate towards zero
+func (f *Forth) _FtoD() {
+	if r, ok := f.fpop(); ok {
+		f.pushDouble(int32(r))
+		f.Next()
+	}
+}
+
+// S>F ( n -- ; F: -- r )
+func (f *Forth) _StoF() {
+	if f.fpush(float64(asint16(f.Pop()))) {
+		f.Next()
+	}
+}
+
+// F>S ( -- n ; F: r -- ) truncate towards zero
+func (f *Forth) _FtoS() {
+	if r, ok := f.fpop(); ok {
+		f.Push(uint16(int32(r)))
+		f.Next()
+	}
+}
+
+/*
+Convert s to a float.  With text set s must have the syntax the text
+interpreter accepts for float literals, which needs an E.  Otherwise it is
+the syntax of >FLOAT, where blanks mean 0 and the exponent is optional.
+*/
+func parseFloat(s string, text bool) (float64, bool) {
+	if !text && strings.Trim(s, " ") == "" {
+		return 0, true
+	}
+	mant, exp := s, ""
+	i := strings.IndexAny(s, "EeDd")
+	if i >= 0 {
+		mant, exp = s[:i], s[i+1:]
+	} else if text {
+		return 0, false
+	} else if j := strings.LastIndexAny(s, "+-"); j > 0 {
+		mant, exp = s[:j], s[j:]
+	}
+	digits := func(s string, point bool) bool {
+		n := 0
+		for _, c := range s {
+			switch {
+			case c >= '0' && c <= '9':
+				n += 1
+			case c == '.' && point:
+				point = false
+			default:
+				return false
+			}
+		}
+		return n > 0
+	}
+	if len(mant) > 0 && (mant[0] == '-' || mant[0] == '+') {
+		mant = mant[1:]
+		if s[0] == '-' {
+			mant = "-" + mant
+		}
+	}
+	if !digits(strings.TrimPrefix(mant, "-"), true) {
+		return 0, false
+	}
+	if len(exp) > 0 && (exp[0] == '-' || exp[0] == '+') {
+		if len(exp) > 1 && !digits(exp[1:], false) {
+			return 0, false
+		}
+	} else if exp != "" && !digits(exp, false) {
+		return 0, false
+	}
+	if exp == "" || exp == "+" || exp == "-" {
+		exp = "0"
+	}
+	r, err := strconv.ParseFloat(mant+"e"+exp, 64)
+	if err != nil && r == 0 {
+		return 0, false
+	}
+	return r, true
+}
+
+// >FLOAT ( b u -- t | F ; F: -- r | ) convert a string to a float
+func (f *Forth) _ToFloat() {
+	u := f.Pop()
+	b := f.Pop()
+	if !f.inMemory(b, int(u)) {
+		return
+	}
+	r, ok := parseFloat(string(f.Memory[b:b+u]), false)
+	if !ok {
+		f.Push(0)
+		f.Next()
+		return
+	}
+	if f.fpush(r) {
+		f.Push(asuint16(-1))
+		f.Next()
+	}
+}
+
+// (fnumber) ( b u -- t | F ; F: -- r | ) convert a float literal in decimal
+func (f *Forth) _Fnumber() {
+	u := f.Pop()
+	b := f.Pop()
+	if !f.inMemory(b, int(u)) {
+		return
+	}
+	r, ok := parseFloat(string(f.Memory[b:b+u]), true)
+	if !ok || f.WordPtr(f.userAddr("BASE")) != 10 {
+		f.Push(0)
+		f.Next()
+		return
+	}
+	if f.fpush(r) {
+		f.Push(asuint16(-1))
+		f.Next()
+	}
+}
+
+// REPRESENT ( b u -- n f1 f2 ; F: r -- ) store the u most significant
+// digits of r at b, return the exponent, the sign and if r is valid
+func (f *Forth) _Represent() {
+	u := int(f.Pop())
+	b := f.Pop()
+	if !f.inMemory(b, u) {
+		return
+	}
+	r, ok := f.fpop()
+	if !ok {
+		return
+	}
+	neg := math.Signbit(r)
+	if math.IsNaN(r) || math.IsInf(r, 0) || u < 1 {
+		f.Push(0)
+		f.Push(flag(neg))
+		f.Push(0)
+		f.Next()
+		return
+	}
+	s := strconv.FormatFloat(math.Abs(r), 'e', u-1, 64)
+	i := strings.Index(s, "e")
+	e, _ := strconv.Atoi(s[i+1:])
+	digits := strings.Replace(s[:i], ".", "", 1)
+	if r == 0 {
+		e = -1
+	}
+	copy(f.Memory[b:], digits)
+	f.Push(uint16(e + 1))
+	f.Push(flag(neg))
+	f.Push(asuint16(-1))
+	f.Next()
+}
+
+func flag(b bool) uint16 {
+	if b {
+		return asuint16(-1)
+	}
+	return 0
+}
+
+/*
+Format r with the significant digits of PRECISION.  Modes are 'f' fixed
+point, 's' scientific and 'e' engineering.
+*/
+func formatFloat(r float64, mode byte, precision int) string {
+	if math.IsNaN(r) || math.IsInf(r, 0) {
+		return strconv.FormatFloat(r, 'g', -1, 64)
+	}
+	s := strconv.FormatFloat(r, 'e', precision-1, 64)
+	i := strings.Index(s, "e")
+	e, _ := strconv.Atoi(s[i+1:])
+	if mode == 'f' && e < 40 && e > -precision-6 {
+		r, _ = strconv.ParseFloat(s, 64)
+		s = strconv.FormatFloat(r, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += "."
+		}
+		return s
+	}
+	sign, digits := "", strings.Replace(s[:i], ".", "", 1)
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+	digits = strings.TrimRight(digits, "0")
+	k := 1 // digits before the point
+	if mode == 'e' {
+		k += (e%3 + 3) % 3
+		e -= k - 1
+	}
+	for len(digits) < k {
+		digits += "0"
+	}
+	m := sign + digits[:k] + "." + digits[k:]
+	return m + "E" + strconv.Itoa(e)
+}
+
+// return a primitive ( -- b u ; F: r -- ) formatting r at PAD
+func (f *Forth) fformat(mode byte) fn {
+	return func() {
+		r, ok := f.fpop()
+		if !ok {
+			return
+		}
+		s := formatFloat(r, mode, f.precision)
+		pad := f.WordPtr(f.userAddr("CP")) + 80
+		if !f.inMemory(pad, len(s)) {
+			return
+		}
+		copy(f.Memory[pad:], s)
+		f.Push(pad)
+		f.Push(uint16(len(s)))
+		f.Next()
+	}
+}
+
+// PRECISION ( -- u ) number of significant digits displayed by F. FE. FS.
+func (f *Forth) _Precision() {
+	f.Push(uint16(f.precision))
+	f.Next()
+}
+
+// SET-PRECISION ( u -- ) set the number of significant digits to display
+func (f *Forth) _SetPrecision() {
+	u := int(f.Pop())
+	if u < 1 {
+		u = 1
+	}
+	if u > 17 {
+		u = 17
+	}
+	f.precision = u
+	f.Next()
+}
@@ -0,0 +1,133 @@
+package eforth
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseFloat(t *testing.T) {
+	tests := []struct {
+		s    string
+		text bool
+		r    float64
+		ok   bool
+	}{
+		{"1E", true, 1, true},
+		{"1.5E3", true, 1500, true},
+		{"-2.5e-2", true, -0.025, true},
+		{"+1E+2", true, 100, true},
+		{".5E0", true, 0.5, true},
+		{"1.5", true, 0, false},
+		{"1.5", false, 1.5, true},
+		{"1.5-3", false, 0.0015, true},
+		{"  ", false, 0, true},
+		{"E5", true, 0, false},
+		{"1E5X", true, 0, false},
+		{"1.2.3E0", true, 0, false},
+		{"", true, 0, false},
+	}
+	for _, v := range tests {
+		r, ok := parseFloat(v.s, v.text)
+		if ok != v.ok || ok && r != v.r {
+			t.Error(v.s, "converted to", r, ok, "instead of", v.r, v.ok)
+		}
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		r    float64
+		mode byte
+		good string
+	}{
+		{3, 'f', "3."},
+		{0.1 + 0.2, 'f', "0.3"},
+		{-1.25, 'f', "-1.25"},
+		{1500, 's', "1.5E3"},
+		{0.00025, 's', "2.5E-4"},
+		{12345, 'e', "12.345E3"},
+		{0.00025, 'e', "250.E-6"},
+		{math.Inf(1), 'f', "+Inf"},
+	}
+	for _, v := range tests {
+		if s := formatFloat(v.r, v.mode, 15); s != v.good {
+			t.Error(v.r, string(v.mode), "formatted as", s, "instead of", v.good)
+		}
+	}
+}
+
+func TestFloatStack(t *testing.T) {
+	_, f := runForth("1.5E0 2.5E0 F+ 2E0 FSQRT FDEPTH")
+	if x := f.Pop(); x != 2 {
+		t.Fatal("FDEPTH should be 2 but is", x)
+	}
+	if r := f.FPop(); r != math.Sqrt2 {
+		t.Fatal("2E0 FSQRT should be", math.Sqrt2, "but is", r)
+	}
+	if r := f.FPop(); r != 4 {
+		t.Fatal("1.5E0 2.5E0 F+ should be 4 but is", r)
+	}
+	f.FPush(7)
+	if f.FDepth() != 1 || f.FPop() != 7 {
+		t.Fatal("FPush and FPop should round trip")
+	}
+	for i := 0; i < FSTACKK; i++ {
+		if err := f.FPush(float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.FPush(16); err == nil || f.FDepth() != FSTACKK {
+		t.Fatal("FPush should not push past FSTACKK floats")
+	}
+}
+
+func TestFloatStackReset(t *testing.T) {
+	o, f := NewForth("1E0 2E0 nosuch\rFDEPTH .\r3E0 4E0 ABORT\rFDEPTH .\rBYE\r")
+	f.Main()
+	if !strings.Contains(o.String(), "FDEPTH . 0 ok") || strings.Count(o.String(), "FDEPTH . 0 ok") != 2 {
+		t.Fatal("an error and ABORT should empty the float stack but printed", o)
+	}
+}
+
+func TestFloatWords(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"1E0 3E0 F/ F.", " 0.333333333333333"},
+		{"2E0 10E0 F** F. -2.5E0 FABS F.", " 1024. 2.5"},
+		{"1E 2E FSWAP F- F. 1E 2E FOVER F. F. F.", " 1. 1. 2. 1."},
+		{"1E 2E 3E FROT F. F. F.", " 1. 3. 2."},
+		{"2.5E0 FLOOR F. -2.5E0 FROUND F. 3.5E0 FROUND F.", " 2. -2. 4."},
+		{"1E 2E F< . 2E 1E F< . -1E F0< . 0E F0= .", " -1 0 -1 -1"},
+		{"1E 1.05E 0.1E F~ . 1E 1.5E 0.1E F~ .", " -1 0"},
+		{"100000. D>F F. 2.7E5 F>D D. -7 S>F F>S .", " 100000. 270000 -7"},
+		{": pi 3.14159E0 ; pi F.", " 3.14159"},
+		{": fl [ 2E0 ] FLITERAL 7 ; fl . F.", " 7 2."},
+		{": mixed 1 2. 3E ; mixed F. D. .", " 3. 2 1"},
+		{"6.5E FCONSTANT six six six F+ F.", " 13."},
+		{"FVARIABLE fv 4.5E fv F! fv F@ F.", " 4.5"},
+		{"HERE 1.5E0 F, F@ F.", " 1.5"},
+		{"PAD 0.75E SF! PAD SF@ F.", " 0.75"},
+		{"2 FLOATS . 1 SFLOATS . 8 FLOAT+ .", " 16 4 16"},
+		{"1234.5E FS. 1234.5E FE.", " 1.2345E3 1.2345E3"},
+		{"3 SET-PRECISION 2E FSQRT F. PRECISION .", " 1.41 3"},
+		{"PAD 5 12.345E REPRESENT . . . SPACE PAD 5 TYPE", " -1 0 2 12345"},
+		{"0E FSIN F. 0E FSINCOS F. F.", " 0. 1. 0."},
+		{`: s $" 2.5-1" COUNT ; s >FLOAT . F.`, " -1 0.25"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.HasPrefix(out, v.good) {
+			t.Error(v.src, "should print", v.good, "but printed", out)
+		}
+	}
+}
+
+func TestFloatUnderflow(t *testing.T) {
+	o, f := NewForth("FDROP\rBYE\r")
+	f.Main()
+	if !strings.Contains(o.String(), " float stack underflow ?") {
+		t.Fatal("FDROP on an empty float stack should throw but printed", o.String())
+	}
+}
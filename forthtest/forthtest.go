@@ -0,0 +1,48 @@
+/*
+Package forthtest runs the T{ ... -> ... }T tests of Forth source files
+under go test.
+*/
+package forthtest
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hagna/eforth"
+)
+
+/*
+Interpret every file matching pattern, like testdata/*.fth, in a new Forth
+as a subtest of t.  Every T{ ... -> ... }T in them that fails is an error
+telling the file and line, as is an error interpreting the file.
+
+	func TestForth(t *testing.T) { forthtest.TestFiles(t, "testdata/*.fth") }
+*/
+func TestFiles(t *testing.T, pattern string) {
+	t.Helper()
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no files match", pattern)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			o := new(bytes.Buffer)
+			f := eforth.New(strings.NewReader(""), o)
+			f.TestFailed = func(where, msg string) {
+				t.Errorf("%s: %s", where, msg)
+			}
+			if err := f.LoadFile(file); err != nil && err != eforth.ErrBye {
+				t.Error(err)
+			}
+			if t.Failed() && o.Len() > 0 {
+				t.Logf("%s printed:\n%s", file, o)
+			}
+		})
+	}
+}
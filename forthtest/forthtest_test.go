@@ -0,0 +1,9 @@
+package forthtest
+
+import (
+	"testing"
+)
+
+func TestForthFiles(t *testing.T) {
+	TestFiles(t, "../testdata/*.fth")
+}
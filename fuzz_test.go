@@ -0,0 +1,249 @@
+package eforth
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// an Input that always has a return typed, so words reading keys go on
+type returns struct{}
+
+func (returns) ReadKey() (byte, bool) { return 13, true }
+func (returns) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 13
+	}
+	return len(p), nil
+}
+
+// steps a fuzzed input may run
+const fuzzSteps = 100000
+
+// the booted Forth fuzzForth clones, built once in each fuzzing process
+var fuzzBase struct {
+	once sync.Once
+	f    *Forth
+}
+
+/*
+Return a booted Forth for fuzzing that does not open files, wait or print
+and runs at most fuzzSteps steps for Call.  It is a Clone of fuzzBase, so a
+fuzzed input costs little more than running it.
+*/
+func fuzzForth() *Forth {
+	fuzzBase.once.Do(func() {
+		f := New(returns{}, io.Discard)
+		f.StepBudget = fuzzSteps
+		f.boot()
+		fuzzBase.f = f
+	})
+	g := fuzzBase.f.Clone()
+	g.Input, g.Output = returns{}, io.Discard
+	g.Clock = &VirtualClock{}
+	g.prim2func["(open)"] = func() { g.throwMessage("no files when fuzzing") }
+	return g
+}
+
+/*
+Fail unless the code dictionary ends below the name dictionary and the
+names linked from LAST are all in it, the newer ones below the older.
+*/
+func checkPointers(t *testing.T, f *Forth, input string) {
+	t.Helper()
+	cp, np, last := f.pointers()
+	if cp < CODEE || cp > np || last < np || last >= NAMEE {
+		t.Fatalf("%q left CP %X NP %X LAST %X", input, cp, np, last)
+	}
+	for na := last; na != 0; {
+		next := f.WordPtr(na - CELLL)
+		if next != 0 && (next <= na || next >= NAMEE) {
+			t.Fatalf("%q left the name at %X linked to %X", input, na, next)
+		}
+		na = next
+	}
+}
+
+func FuzzNumber(f *testing.F) {
+	for _, s := range []string{"0", "-1", "32767", "$FF", "12.", "-0", "$", "-", "1.5E0", "99999999999"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if len(s) > 255 {
+			t.Skip()
+		}
+		g := fuzzForth()
+		pad, _ := g.Call("PAD")
+		a := uint16(pad[0])
+		g.setCountedString(a, s)
+		res, err := g.Call("NUMBER?", int16(a))
+		if err != nil {
+			t.Fatalf("NUMBER? %q failed: %v", s, err)
+		}
+		n, perr := strconv.ParseInt(s, 10, 16)
+		if perr == nil && s[0] != '+' && (len(res) != 2 || res[0] != int16(n) || res[1] == 0) {
+			t.Errorf("NUMBER? %q should return %d true but returned %v", s, n, res)
+		}
+		checkPointers(t, g, s)
+	})
+}
+
+/*
+Words that store to any address or take any amount of the dictionary, they
+can overwrite the dictionary as they are meant to.  The dictionary is only
+checked after an input that uses none of them.
+*/
+var unsafeWords = map[string]bool{
+	"!": true, "C!": true, "+!": true, "2!": true, "F!": true, "SF!": true, "DF!": true,
+	"CMOVE": true, "CMOVE>": true, "FILL": true, "BLANK": true, "PACK$": true,
+	"SP!": true, "RP!": true, "lp!": true, "DEFER!": true, "HOLD": true,
+	"ALLOT": true, ",": true, "F,": true, "TASK": true, "ACTIVATE": true,
+	"EXECUTE": true, "@EXECUTE": true, "call,": true, "accept": true, "EXPECT": true,
+	"COLD": true, "value!": true, "value+!": true, "local!": true, "local+!": true,
+}
+
+func FuzzInterpret(f *testing.F) {
+	for _, s := range []string{
+		"1 2 + .", ": sq DUP * ; 3 sq .", "VARIABLE v 5 v ! v @ .",
+		"0 -1 !", "7 SP!", "-9 RP! 1", "0 lp! {: a :} a", "5 0 0 -7 CMOVE",
+		"' DUP 2 + EXECUTE", "HERE 100 - ALLOT 1 ,", "TASK t 0 t ! t ACTIVATE",
+		": f 0 10 0 DO I + LOOP ; f", `." hi" S" abc" TYPE`, "1.5E0 2E0 F* F.",
+		"HEX FF DECIMAL .", ": r R> R> ; r", "1 0 / .", "-2 @", "0 SP@ @",
+		": x BEGIN AGAIN ; x", "' DUP SEE DUP", "{: a :}", "T{ 1 -> 1 }T",
+		"interpret", "(refill)", "(close)", "1 (error)", "(included)",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		if len(line) > LINEE || strings.ContainsAny(line, "\r\n") {
+			t.Skip()
+		}
+		safe := true
+		for _, w := range strings.Fields(line) {
+			if unsafeWords[strings.ToUpper(w)] || unsafeWords[w] {
+				safe = false
+			}
+		}
+		g := fuzzForth()
+		g.limit = g.steps + fuzzSteps
+		g.Include("fuzz", strings.NewReader(line))
+		g.limit = 0
+		if safe {
+			checkPointers(t, g, line)
+			g.Words()
+		}
+	})
+}
+
+func FuzzWordFromASM(f *testing.F) {
+	for _, s := range []string{
+		"\t\t$COLON\t3,'SQR',SQR\n\t\tDW\tDUPP,STAR,EXIT\n",
+		"\t\t$COLON\tCOMPO+2,'x2',XX\nX1:\t\tDW\tDOLIT,2,STAR\n\t\tDW\tBRAN,X1\n",
+		"\t\t$COLON\t3,'STR',STR\n\t\tD$\tDOTQP,'hello'\n\t\tDW\tEXIT\n",
+		"\t\t$USER\t3,'USR',USR\n",
+		"\t\t$COLON\t1,'?',Q\n\t\tDW\tNOSUCH\n",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, asm string) {
+		g := New(nil, io.Discard)
+		g.WordFromASM(asm)
+		checkPointers(t, g, asm)
+	})
+}
+
+// words random threaded code is made of, they can not store anywhere
+var fuzzWords = []string{
+	"DUP", "DROP", "SWAP", "OVER", "ROT", "?DUP", "+", "-", "*", "/", "MOD",
+	"NEGATE", "ABS", "AND", "OR", "XOR", "0<", "<", "=", "U<", "@", "C@",
+	">R", "R>", "R@", "EXIT", "DEPTH", "UM*", "UM/MOD", "M*", "MAX", "MIN",
+}
+
+/*
+Run the bytes of code as a colon definition: a byte below len(fuzzWords)
+compiles that word, others compile a literal or a branch in the definition.
+*/
+func FuzzThreaded(f *testing.F) {
+	f.Add([]byte{0, 6, 1})
+	f.Add([]byte{100, 22, 23, 24, 25})
+	f.Add([]byte{200, 0, 201, 3, 9, 26})
+	f.Add([]byte{150, 150, 9, 10, 20})
+	f.Fuzz(func(t *testing.T, code []byte) {
+		if len(code) > 200 {
+			t.Skip()
+		}
+		g := fuzzForth()
+		dolist, _ := g.Addr("doLIST")
+		lit, _ := g.Addr("doLIT")
+		bran, _ := g.Addr("branch")
+		qbran, _ := g.Addr("?branch")
+		exit, _ := g.Addr("EXIT")
+		cells := []uint16{CALLL, dolist}
+		var starts []int          // of the words compiled, to branch to
+		targets := map[int]bool{} // cells holding the index of one of starts
+		for i, b := range code {
+			starts = append(starts, len(cells))
+			switch {
+			case int(b) < len(fuzzWords):
+				a, _ := g.Addr(fuzzWords[b])
+				cells = append(cells, a)
+			case b >= 200:
+				to := uint16(int(b-200) % len(code))
+				if i%2 == 0 {
+					cells = append(cells, bran, to)
+				} else {
+					cells = append(cells, qbran, to)
+				}
+				targets[len(cells)-1] = true
+			default:
+				cells = append(cells, lit, uint16(b)*100)
+			}
+		}
+		cells = append(cells, exit)
+		for i := range targets {
+			cells[i] = uint16(starts[cells[i]])
+		}
+		g.beginWord()
+		ca, err := g.allot(uint16(len(cells) * CELLL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, c := range cells {
+			if targets[i] {
+				c = ca + c*CELLL
+			}
+			g.SetWordPtr(ca+uint16(i*CELLL), c)
+		}
+		name := "fuzzed"
+		g.newWord(name, ca, 0)
+		g.endWord()
+		g.Call(name)
+		checkPointers(t, g, string(code))
+	})
+}
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"-2 @", "invalid memory address ?"},
+		{"-3 EXECUTE", "invalid memory address ?"},
+		{"$4000 C@", "invalid memory address ?"},
+		{": t {: a :} 0 lp! a ; 5 t", "invalid memory address ?"},
+		{"-2 SP!", "stack underflow ?"},
+		{"1 0 1 0 M*/", "division by zero ?"},
+		{": u BEGIN 1 AGAIN ; u", "stack overflow ?"},
+		{": d 1 RECURSE ; d", "return stack overflow ?"},
+	}
+	for _, v := range tests {
+		o, f := NewForth(v.src + "\r1 2 + .\rBYE\r")
+		f.Main()
+		if !strings.Contains(o.String(), v.good) || !strings.Contains(o.String(), " 3") {
+			t.Errorf("%s should throw %q and go on but printed %q", v.src, v.good, o)
+		}
+		checkPointers(t, f, v.src)
+	}
+}
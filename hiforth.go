@@ -8,8 +8,6 @@ import (
 	"strings"
 )
 
-var asm2forth map[string]string
-
 type codeitem struct {
 	val    []byte
 	offset uint16
@@ -94,18 +92,21 @@ func (c *codeList) addString(s string) {
 
 // gives labelled items the right addresses
 // which we don't do till the codelist is complete
-func (c *codeList) fixLabels() {
+func (c *codeList) fixLabels() error {
 	slice := *c.lst
 	for _, val := range slice {
 		if val.li != -1 {
+			if val.li >= len(slice) {
+				return errors.New(fmt.Sprintf("label %s is past the end", val.name))
+			}
 			off := slice[val.li].offset
 			binary.LittleEndian.PutUint16(val.val, off)
 		}
 	}
-
+	return nil
 }
 
-func (f *Forth) compileWords(name string, words []string, labels map[string]uint16, bitmask int) (err error) {
+func (f *Forth) compileWords(name string, words []string, labels map[string]uint16, bitmask int, effect string) (err error) {
 	err = nil
 	startaddr := CODEE + (CELLL * f.prims)
 	codelist := codeList{&[]codeitem{}, startaddr}
@@ -139,7 +140,7 @@ func (f *Forth) compileWords(name string, words []string, labels map[string]uint
 		}},
 		{"ASM", func(w string) error {
 			res := errors.New(fmt.Sprintf("ERROR: No word corresponds to %s", w))
-			if name, ok := asm2forth[w]; ok {
+			if name, ok := f.asm2forth[w]; ok {
 				if addr, e := f.Addr(name); e == nil {
 					codelist.add(w, addr)
 					return e
@@ -159,7 +160,7 @@ func (f *Forth) compileWords(name string, words []string, labels map[string]uint
 		}},
 		{"STRING", func(w string) error {
 			res := errors.New(fmt.Sprintf("could not find string in %s", w))
-			if strings.HasPrefix(w, "'") && strings.HasSuffix(w, "'") && len(w) > 3 {
+			if strings.HasPrefix(w, "'") && strings.HasSuffix(w, "'") && len(w) > 3 && len(w) < 258 {
 				codelist.addString(w)
 				return nil
 			}
@@ -174,6 +175,9 @@ func (f *Forth) compileWords(name string, words []string, labels map[string]uint
 		}
 		return errors.New(fmt.Sprintf("no way to parse %s", word))
 	}
+	if name == "" || len(name) > 31 {
+		return errors.New(fmt.Sprintf(`bad name "%s"`, name))
+	}
 	var nprims uint16
 	nprims = 0
 	for _, word := range words {
@@ -182,7 +186,13 @@ func (f *Forth) compileWords(name string, words []string, labels map[string]uint
 			return err
 		}
 	}
-	codelist.fixLabels()
+	if err := codelist.fixLabels(); err != nil {
+		return err
+	}
+	header := uint16((len(name)/CELLL + 3) * CELLL)
+	if int(startaddr)+int(codelist.size()) > int(f._NP-header) {
+		return errors.New(fmt.Sprintf("no room in the dictionary for %s", name))
+	}
 	codelist.intoForth(f)
 	//codelist.println()
 	nprims = codelist.size() / CELLL
@@ -190,11 +200,13 @@ func (f *Forth) compileWords(name string, words []string, labels map[string]uint
 		fmt.Println("BUGBUG ***** odd length for colon def", codelist.size(), codelist.lst)
 	}
 	f.newWord(name, startaddr, bitmask)
+	f.declare(name, effect)
 	f.prims = f.prims + nprims
 	return err
 }
 
 func (f *Forth) doUserVariables() {
+	f.own()
 	QRX, _ := f.Addr("?RX")
 	TXSTO, _ := f.Addr("TX!")
 	ACCEP, _ := f.Addr("accept")
@@ -267,15 +279,21 @@ Compile a forth word from a definition that looks like this:
 
 */
 func (f *Forth) WordFromASM(asm string) (err error) {
+	if f.binding {
+		return nil
+	}
+	f.own()
 	words := []string{}
 	labels := make(map[string]uint16)
 	bitmask := 0
 	name := ""
+	comment, effect := "", "" // the stack comment read and that of name
 	err = nil
 	restart := func() {
 		words = []string{}
 		labels = make(map[string]uint16)
 		bitmask = 0
+		effect = ""
 	}
 	getname := func(line string) string {
 		for _, sep := range []string{"'", `"`} {
@@ -298,6 +316,9 @@ func (f *Forth) WordFromASM(asm string) (err error) {
 	inlinestring := func(line string) string {
 		i := strings.Index(line, "'")
 		j := strings.LastIndex(line, "'")
+		if i == j {
+			return "" // no way to parse it
+		}
 		spart := line[i : j+1]
 		return spart
 	}
@@ -309,6 +330,9 @@ func (f *Forth) WordFromASM(asm string) (err error) {
 			}
 			return false
 		})
+		if c := listingComment(line); c != "" {
+			comment = c
+		}
 		toks := fields
 		// remove comments
 		var i int
@@ -327,12 +351,13 @@ func (f *Forth) WordFromASM(asm string) (err error) {
 			switch {
 			case tok == "$COLON":
 				if name != "" {
-					if err = f.compileWords(name, words, labels, bitmask); err != nil {
+					if err = f.compileWords(name, words, labels, bitmask, effect); err != nil {
 						return err
 					}
 					restart()
 				}
 				name = getname(line)
+				effect, comment = comment, ""
 				vname := fields[len(fields)-1]
 				if strings.Contains(line, "COMPO+") {
 					bitmask |= COMPO
@@ -340,20 +365,24 @@ func (f *Forth) WordFromASM(asm string) (err error) {
 				if strings.Contains(line, "IMEDD+") {
 					bitmask |= IMEDD
 				}
-				asm2forth[vname] = name
+				f.asm2forth[vname] = name
 				words = append(words, []string{"CALLL", "doLIST"}...)
 				break tokenloop
 			case tok == "$USER":
 				if name != "" {
-					if err = f.compileWords(name, words, labels, bitmask); err != nil {
+					if err = f.compileWords(name, words, labels, bitmask, effect); err != nil {
 						return err
 					}
 					restart()
 				}
+				if len(toks) < 2 || len(toks[1]) < 2 || len(fields) < 4 || f._USER >= US {
+					return errors.New(fmt.Sprintf("bad user variable: %s", line))
+				}
 				name = toks[1]
 				name = name[1 : len(name)-1]
+				effect, comment = comment, ""
 				vname := fields[3]
-				asm2forth[vname] = name
+				f.asm2forth[vname] = name
 				words = append(words, []string{"CALLL", "doLIST", "doUSER", strconv.Itoa(int(f._USER))}...)
 				//				fmt.Println("user variable", name, " offset is", f._USER)
 				f._USER += CELLL
@@ -370,6 +399,9 @@ func (f *Forth) WordFromASM(asm string) (err error) {
 				words = append(words, toks...)
 				break tokenloop
 			case tok == "D$":
+				if len(toks) == 0 {
+					return errors.New(fmt.Sprintf("no string: %s", line))
+				}
 				words = append(words, toks[0])
 				words = append(words, inlinestring(line))
 				break tokenloop
@@ -377,7 +409,7 @@ func (f *Forth) WordFromASM(asm string) (err error) {
 		}
 	}
 	f.doUserVariables()
-	err = f.compileWords(name, words, labels, bitmask)
+	err = f.compileWords(name, words, labels, bitmask, effect)
 	f.doUserVariables()
 	return err
 
@@ -415,7 +447,6 @@ func (f *Forth) addHiforth() {
 	for _, v := range constants {
 		f.prim2addr[v.s] = v.v
 	}
-	asm2forth = make(map[string]string)
 	f.macros = make(map[string]fn)
 	f.macros["#TIB"] = func() {
 		f._USER = f._USER + CELLL
@@ -465,7 +496,7 @@ func (f *Forth) addHiforth() {
 		{"STOIO", "!IO"},
 	}
 	for _, v := range amap {
-		asm2forth[v.aword] = v.fword
+		f.asm2forth[v.aword] = v.fword
 	}
 	hiforth := []string{
 		`
@@ -159,6 +159,19 @@ func NewForth(in string) (o *bytes.Buffer, f *Forth) {
 	return
 }
 
+// Interpret the line src followed by BYE and return what it printed after
+// the echo of the line
+func runForth(src string) (out string, f *Forth) {
+	o, f := NewForth(src + " BYE\r")
+	f.Main()
+	out = o.String()
+	i := strings.Index(out, src+" BYE")
+	if i >= 0 {
+		out = out[i+len(src)+4:]
+	}
+	return
+}
+
 func TestDoTqp(t *testing.T) {
 	b := new(bytes.Buffer)
 	f := New(strings.NewReader("BYE"), b)
@@ -175,7 +188,7 @@ func TestDoTqp(t *testing.T) {
 func AddWord(f *Forth, t *testing.T, name string, words ...string) {
 	a := append([]string{"CALLL", "doLIST"}, words...)
 	a = append(a, "EXIT")
-	err := f.compileWords(name, a, nil, 0)
+	err := f.compileWords(name, a, nil, 0, "")
 	if err != nil {
 		t.Fatal("ERROR compileWords:", err)
 	}
@@ -0,0 +1,64 @@
+package eforth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// first bytes of a saved image
+const imageMagic = "eForth image 1\n"
+
+/*
+Write the memory of f to w so LoadImage can start another Forth where f is
+now.  The user area is saved as the cold start values, like Main does, so
+everything defined so far survives COLD.
+*/
+func (f *Forth) SaveImage(w io.Writer) error {
+	m := f.Memory
+	if f.booted {
+		n, _ := f.Addr("ULAST-UZERO")
+		copy(m[0:n], m[UPP:UPP+n])
+	}
+	if _, err := io.WriteString(w, imageMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(f.pcode2word))); err != nil {
+		return err
+	}
+	_, err := w.Write(m[:])
+	return err
+}
+
+/*
+Replace the memory of f with an image written by SaveImage.  The image
+holds no go code, so f must have the primitives of the Forth that saved it.
+*/
+func (f *Forth) LoadImage(r io.Reader) error {
+	magic := make([]byte, len(imageMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != imageMagic {
+		return errors.New("not an eForth image")
+	}
+	var prims uint16
+	if err := binary.Read(r, binary.LittleEndian, &prims); err != nil {
+		return err
+	}
+	if int(prims) != len(f.pcode2word) {
+		return errors.New(fmt.Sprintf("the image has %d primitives instead of %d", prims, len(f.pcode2word)))
+	}
+	var m [EM]byte
+	if _, err := io.ReadFull(r, m[:]); err != nil {
+		return err
+	}
+	f.Memory = m
+	// the cold start CP, NP and LAST are the last cells of the user area
+	n, _ := f.Addr("ULAST-UZERO")
+	f.readPointers(n - 3*CELLL)
+	copy(f.Memory[UPP:UPP+n], f.Memory[0:n])
+	f.booted = true
+	f.locals = nil
+	ca, _ := f.Addr("PRESET")
+	f.run(ca)
+	return nil
+}
@@ -0,0 +1,64 @@
+package eforth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestImage(t *testing.T) {
+	f := New(nil, new(bytes.Buffer))
+	if err := f.Include("lib", strings.NewReader(": sq DUP * ;\n3 VALUE three")); err != nil {
+		t.Fatal(err)
+	}
+	img := new(bytes.Buffer)
+	if err := f.SaveImage(img); err != nil {
+		t.Fatal(err)
+	}
+	o := new(bytes.Buffer)
+	g := New(nil, o)
+	if err := g.LoadImage(bytes.NewReader(img.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Include("use", strings.NewReader("three sq .")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddWord(": cube DUP sq * ;"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Include("cube", strings.NewReader("3 cube .")); err != nil {
+		t.Fatal(err)
+	}
+	g.Input = strings.NewReader("2 cube . BYE\r")
+	g.Main()
+	if !strings.HasPrefix(o.String(), " 9 27") || !strings.Contains(o.String(), "BYE 8") {
+		t.Fatal("the image should keep sq and three but printed", o.String())
+	}
+}
+
+func TestImageErrors(t *testing.T) {
+	f := New(nil, nil)
+	if err := f.LoadImage(strings.NewReader("not an image")); err == nil {
+		t.Fatal("should not load a file that is not an image")
+	}
+	img := new(bytes.Buffer)
+	f.SaveImage(img)
+	g := New(nil, nil)
+	g.AddPrim("extra", func() { g.Next() }, 0)
+	if err := g.LoadImage(img); err == nil {
+		t.Fatal("should not load an image saved with other primitives")
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	o := new(bytes.Buffer)
+	f := New(strings.NewReader("2 3 + .\rnosuch\r1 .\rBYE\r"), o)
+	f.Quiet()
+	f.Main()
+	if s := o.String(); strings.Contains(s, "ok") || strings.Contains(s, "eForth") {
+		t.Fatal("should show no sign on and no ok but printed", s)
+	}
+	if s := o.String(); !strings.Contains(s, " 5") || !strings.Contains(s, "nosuch ?") {
+		t.Fatal("should still print output and errors but printed", s)
+	}
+}
@@ -0,0 +1,260 @@
+package eforth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	LINEE   = 128 // size of the line buffer for included files
+	ERRBUFF = 128 // size of the buffer for error messages raised from go
+)
+
+/*
+An input source nested by INCLUDED.  It keeps the input specification of the
+source it interrupted (TIB, #TIB, >IN and 'PROMPT) to restore it afterwards.
+*/
+type source struct {
+	name  string
+	line  int
+	text  string // current line, copied back into the line buffer after a nested source
+	next  func() (string, error)
+	close func() error
+
+	tib, ntib, in, prompt uint16
+}
+
+func (f *Forth) addInclude() {
+	f.linebuf, _ = f.allot(LINEE)
+	f.errbuf, _ = f.allot(ERRBUFF)
+
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"(open)", "OPENS", f._Open, 0},
+		{"(refill)", "REFIL", f._Refill, 0},
+		{"(close)", "CLOSS", f._Close, 0},
+		{"(error)", "SERRS", f._Error, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Source files
+
+;   interpret	( -- )
+;		Interpret the current input source line by line to the end.
+
+		$COLON	9,'interpret',INTRP
+INTR1:		DW	REFIL
+		DW	QBRAN,INTR2
+		DW	EVAL
+		DW	BRAN,INTR1
+INTR2:		DW	EXIT
+
+;   (included)	( -- )
+;		Interpret the current input source and close it, also on errors.
+
+		$COLON	10,'(included)',PINCL
+		DW	DOLIT,INTRP,CATCH,QDUP	;interpret the whole source
+		DW	QBRAN,PINC1
+		DW	SERRS,CLOSS,THROW	;tell where, unnest, pass it on
+PINC1:		DW	CLOSS,EXIT
+
+;   INCLUDED	( b u -- )
+;		Interpret the source file named by the string b u.
+
+		$COLON	8,'INCLUDED',INCLD
+		DW	OPENS,PINCL,EXIT
+
+;   INCLUDE	( -- ; <string> )
+;		Interpret the source file named by the next word.
+
+		$COLON	7,'INCLUDE',INCLU
+		DW	BLANK,PARSE,INCLD,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+// returned by LoadFile and Include when the source executes BYE
+var ErrBye = errors.New("BYE")
+
+/*
+Interpret the Forth source file at path as if it had been INCLUDEd.  Errors
+are returned as "file:line: word ?" and leave the interpreter ready for more
+input.
+*/
+func (f *Forth) LoadFile(path string) error {
+	f.boot()
+	if err := f.openFile(path); err != nil {
+		return err
+	}
+	return f.included()
+}
+
+/*
+Interpret the Forth source read from r like LoadFile, name is the file name
+given in errors.
+*/
+func (f *Forth) Include(name string, r io.Reader) error {
+	f.boot()
+	f.pushReader(name, r, nil)
+	return f.included()
+}
+
+// interpret the source pushed last and close it
+func (f *Forth) included() error {
+	ca, _ := f.Addr("(included)")
+	e, ok := f.catch(ca)
+	if !ok {
+		return ErrBye
+	}
+	if e != 0 {
+		return errors.New(fmt.Sprintf("%s ?", f.countedString(e)))
+	}
+	return nil
+}
+
+func (f *Forth) openFile(path string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	f.pushReader(path, fd, fd.Close)
+	return nil
+}
+
+// make the lines read from r the input source
+func (f *Forth) pushReader(name string, r io.Reader, close func() error) {
+	b := bufio.NewReader(r)
+	f.pushSource(&source{
+		name: name,
+		next: func() (string, error) {
+			line, err := b.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			return strings.TrimRight(line, "\r\n"), err
+		},
+		close: close,
+	})
+}
+
+// save the input specification in s and make s the input source
+func (f *Forth) pushSource(s *source) {
+	ntib := f.userAddr("#TIB")
+	prompt := f.userAddr("'PROMPT")
+	s.ntib = f.WordPtr(ntib)
+	s.tib = f.WordPtr(ntib + CELLL)
+	s.in = f.WordPtr(f.userAddr(">IN"))
+	s.prompt = f.WordPtr(prompt)
+	f.SetWordPtr(prompt, 0) // no ' ok' for every line
+	f.sources = append(f.sources, s)
+}
+
+// close the input source and restore the one it interrupted
+func (f *Forth) popSource() {
+	s := f.sources[len(f.sources)-1]
+	f.sources = f.sources[:len(f.sources)-1]
+	if s.close != nil {
+		s.close()
+	}
+	ntib := f.userAddr("#TIB")
+	f.SetWordPtr(ntib, s.ntib)
+	f.SetWordPtr(ntib+CELLL, s.tib)
+	f.SetWordPtr(f.userAddr(">IN"), s.in)
+	f.SetWordPtr(f.userAddr("'PROMPT"), s.prompt)
+	if len(f.sources) > 0 {
+		copy(f.Memory[f.linebuf:], f.sources[len(f.sources)-1].text)
+	}
+}
+
+// (open) ( b u -- ) open the file named by b u and make it the input source
+func (f *Forth) _Open() {
+	u := f.Pop()
+	b := f.Pop()
+	if !f.inMemory(b, int(u)) {
+		return
+	}
+	name := string(f.Memory[b : b+u])
+	if err := f.openFile(name); err != nil {
+		f.throwMessage(name)
+		return
+	}
+	f.Next()
+}
+
+// (refill) ( -- t | F ) read the next line of the input source into the line buffer
+func (f *Forth) _Refill() {
+	if len(f.sources) == 0 {
+		f.throwMessage("no input source")
+		return
+	}
+	s := f.sources[len(f.sources)-1]
+	line, err := s.next()
+	if err != nil {
+		f.Push(0)
+		f.Next()
+		return
+	}
+	s.line += 1
+	if len(line) > LINEE {
+		f.throwMessage("line too long")
+		return
+	}
+	s.text = line
+	copy(f.Memory[f.linebuf:], line)
+	ntib := f.userAddr("#TIB")
+	f.SetWordPtr(ntib, uint16(len(line)))
+	f.SetWordPtr(ntib+CELLL, f.linebuf)
+	f.SetWordPtr(f.userAddr(">IN"), 0)
+	f.Push(asuint16(-1))
+	f.Next()
+}
+
+// (close) ( -- ) close the input source and go back to the one it interrupted
+func (f *Forth) _Close() {
+	if len(f.sources) == 0 {
+		f.throwMessage("no input source")
+		return
+	}
+	f.popSource()
+	f.Next()
+}
+
+// (error) ( err# -- err# ) prefix the error message with the file name and line
+// number where it happened, only the innermost source does this
+func (f *Forth) _Error() {
+	e := f.Pop()
+	null, _ := f.Addr("NULL$")
+	if e == f.errbuf && f.errwhere || e == null+3*CELLL {
+		f.Push(e)
+		f.Next()
+		return
+	}
+	if len(f.sources) == 0 {
+		f.throwMessage("no input source")
+		return
+	}
+	s := f.sources[len(f.sources)-1]
+	msg := fmt.Sprintf("%s:%d: %s", s.name, s.line, f.countedString(e))
+	if len(msg) > ERRBUFF-1 {
+		msg = msg[:ERRBUFF-1]
+	}
+	f.setCountedString(f.errbuf, msg)
+	f.errwhere = true
+	f.Push(f.errbuf)
+	f.Next()
+}
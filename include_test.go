@@ -0,0 +1,133 @@
+package eforth
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSource(t *testing.T, dir, name, text string) string {
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	b := writeSource(t, dir, "b.fth", ": cube DUP sq * ;\r\n3 cube\r\n")
+	a := writeSource(t, dir, "a.fth", "\\ squares\n: sq DUP * ;\n7 sq\nINCLUDE "+b+"\n")
+	o := new(bytes.Buffer)
+	f := New(nil, o)
+	if err := f.LoadFile(a); err != nil {
+		t.Fatal(err)
+	}
+	if x := f.Pop(); x != 27 {
+		t.Fatal("3 cube should have left 27 but left", x)
+	}
+	if x := f.Pop(); x != 49 {
+		t.Fatal("7 sq should have left 49 but left", x)
+	}
+	if o.Len() != 0 {
+		t.Fatal("loading should be quiet but printed", o.String())
+	}
+}
+
+func TestLoadFileError(t *testing.T) {
+	dir := t.TempDir()
+	d := writeSource(t, dir, "d.fth", ": x 1 ;\nx nosuch\n")
+	c := writeSource(t, dir, "c.fth", "1 2\nINCLUDE "+d+"\n3\n")
+	f := New(nil, new(bytes.Buffer))
+	err := f.LoadFile(c)
+	if err == nil {
+		t.Fatal("nosuch should not load")
+	}
+	if good := d + ":2: nosuch ?"; err.Error() != good {
+		t.Fatal("error should be", good, "but was", err)
+	}
+	if len(f.sources) != 0 {
+		t.Fatal("all sources should be closed but", len(f.sources), "are open")
+	}
+	if err := f.LoadFile(filepath.Join(dir, "none.fth")); err == nil {
+		t.Fatal("should not load a missing file")
+	}
+}
+
+// the rest of the terminal line is interpreted after the file
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSource(t, dir, "a.fth", ": ten 10 ;\n")
+	i := strings.NewReader("INCLUDE " + a + " ten 5 BYE\r")
+	o := new(bytes.Buffer)
+	f := New(i, o)
+	f.Main()
+	t.Log(o.String())
+	if x := f.Pop(); x != 5 {
+		t.Fatal("should have left 5 on the stack but left", x)
+	}
+	if x := f.Pop(); x != 10 {
+		t.Fatal("should have left 10 on the stack but left", x)
+	}
+}
+
+func TestIncludeError(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSource(t, dir, "a.fth", "\n: x nosuch ;\n")
+	i := strings.NewReader("INCLUDE " + a + "\r 7 BYE\r")
+	o := new(bytes.Buffer)
+	f := New(i, o)
+	f.Main()
+	t.Log(o.String())
+	if good := " " + a + ":2: nosuch ? "; !strings.Contains(o.String(), good) {
+		t.Fatal("output should report", good)
+	}
+	if x := f.Pop(); x != 7 {
+		t.Fatal("should still interpret after the error and leave 7 but left", x)
+	}
+}
+
+// definitions from LoadFile survive COLD in Main
+func TestLoadFileMain(t *testing.T) {
+	dir := t.TempDir()
+	a := writeSource(t, dir, "a.fth", ": ten 10 ;\n")
+	f := New(strings.NewReader("ten BYE\r"), new(bytes.Buffer))
+	if err := f.LoadFile(a); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	if x := f.Pop(); x != 10 {
+		t.Fatal("ten should have left 10 but left", x)
+	}
+}
+
+func TestIncludeReader(t *testing.T) {
+	o := new(bytes.Buffer)
+	f := New(nil, o)
+	if err := f.Include("sq", strings.NewReader(": sq DUP * ;\n5 sq .")); err != nil {
+		t.Fatal(err)
+	}
+	if o.String() != " 25" {
+		t.Fatal("should print 25 but printed", o.String())
+	}
+	err := f.Include("bad", strings.NewReader("1\n2 nosuch"))
+	if err == nil || err.Error() != "bad:2: nosuch ?" {
+		t.Fatal("should fail on nosuch but returned", err)
+	}
+	if err := f.Include("bye", strings.NewReader("BYE 3 .")); err != ErrBye {
+		t.Fatal("BYE should return ErrBye but returned", err)
+	}
+}
+
+// the words of the source files throw from the terminal, where there is no source
+func TestNoSource(t *testing.T) {
+	for _, w := range []string{"interpret", "(refill)", "(close)", "1 (error)", "(included)"} {
+		o, f := NewForth(w + "\r1 2 + .\rBYE\r")
+		f.Main()
+		if !strings.Contains(o.String(), "no input source ?") || !strings.Contains(o.String(), " 3") {
+			t.Errorf("%s should throw no input source and go on but printed %q", w, o)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package eforth
+
+import (
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+)
+
+// how many interrupts there are, numbered from 0
+const INTERRUPTS = 16
+
+/*
+Interrupts are raised by go code with Interrupt and taken between Steps.
+Taking interrupt n pushes IP and WP on the return stack and runs the word
+stored for n with INTERRUPT!, which returns through (reti) to RETI.  RETI
+pops WP and IP so the word interrupted goes on.  Interrupts without a word
+are dropped, the lowest number is taken first and no interrupt is taken
+while a handler runs, while DI holds them or while go runs a word.
+*/
+func (f *Forth) addInterrupts() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"INTERRUPT!", "INTST", f._InterruptStore, 0},
+		{"DI", "DI", f._DI, 0},
+		{"EI", "EI", f._EI, 0},
+		{"RETI", "RETI", f._RETI, COMPO},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Interrupts
+
+;   (vectors)	( -- a )
+;		Hold the code address of the handler of each interrupt, 0 if none.
+
+		$COLON	9,'(vectors)',VECTS
+		DW	DOVAR
+		DW	0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0	;INTERRUPTS
+
+;   (reti)	( -- )
+;		Where a handler returns to.
+
+		$COLON	COMPO+6,'(reti)',PRETI
+		DW	RETI
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+/*
+Raise interrupt n, from 0 to INTERRUPTS-1, so the word stored for it with
+INTERRUPT! runs at the next Step it can.  It may be called from any
+goroutine, a timer or an I/O event, raising one already pending does
+nothing.
+*/
+func (f *Forth) Interrupt(n int) {
+	if n < 0 || n >= INTERRUPTS {
+		return
+	}
+	for {
+		p := atomic.LoadUint32(&f.pending)
+		if atomic.CompareAndSwapUint32(&f.pending, p, p|1<<uint(n)) {
+			return
+		}
+	}
+}
+
+/*
+Latch the interrupts raised since the last Step, so the step they are seen
+at does not depend on other goroutines after that.
+*/
+func (f *Forth) latch() {
+	p := atomic.SwapUint32(&f.pending, 0)
+	f.irq |= p
+	f.recordInterrupts(p)
+}
+
+/*
+Take the lowest interrupt latched if it can be taken now, before the Step
+runs WP.  A handler that threw past the IP and WP it saved has ended.
+*/
+func (f *Forth) interrupt() {
+	if f.irqRP != 0 {
+		if f.RP <= f.irqRP {
+			return
+		}
+		f.irqRP = 0
+	}
+	if f.irq == 0 || f.masked || f.nested > 0 {
+		return
+	}
+	n := bits.TrailingZeros32(f.irq)
+	f.irq &^= 1 << uint(n)
+	ca := f.WordPtr(f.vector(n))
+	if ca == 0 {
+		return
+	}
+	reti, _ := f.Addr("(reti)")
+	for _, r := range []uint16{f.IP, f.WP} {
+		f.RP -= CELLL
+		f.SetWordPtr(f.RP, r)
+	}
+	f.irqRP = f.RP
+	f.IP = reti + 2*CELLL
+	f.WP = ca
+}
+
+// INTERRUPT! ( ca n -- ) run the word at ca when interrupt n is taken, 0 for none
+func (f *Forth) _InterruptStore() {
+	n := asint16(f.Pop())
+	ca := f.Pop()
+	if n < 0 || n >= INTERRUPTS {
+		f.throwMessage("interrupt number out of range")
+		return
+	}
+	f.SetWordPtr(f.vector(int(n)), ca)
+	f.Next()
+}
+
+// return the address of the handler of interrupt n in (vectors)
+func (f *Forth) vector(n int) uint16 {
+	vectors, _ := f.Addr("(vectors)")
+	return vectors + 3*CELLL + uint16(n)*CELLL
+}
+
+// DI ( -- ) hold the interrupts raised until EI
+func (f *Forth) _DI() {
+	f.masked = true
+	f.Next()
+}
+
+// EI ( -- ) take interrupts again
+func (f *Forth) _EI() {
+	f.masked = false
+	f.Next()
+}
+
+// RETI ( -- ) end a handler, pop the WP and IP of the word interrupted
+func (f *Forth) _RETI() {
+	if f.irqRP == 0 || f.RP != f.irqRP {
+		f.throwMessage("RETI outside an interrupt")
+		return
+	}
+	f.WP = f.WordPtr(f.RP)
+	f.IP = f.WordPtr(f.RP + CELLL)
+	f.RP += 2 * CELLL
+	f.irqRP = 0
+}
@@ -0,0 +1,74 @@
+package eforth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a Forth with RAISE ( n -- ) raising interrupt n from go
+func raiseForth(src string) string {
+	o, f := NewForth(src + "\rBYE\r")
+	f.Bind("RAISE", func(n int) { f.Interrupt(n) })
+	f.Main()
+	return o.String()
+}
+
+func TestInterrupts(t *testing.T) {
+	def := "VARIABLE n 0 n ! : h 1 n +! ; : g 10 n +! ;\r"
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"' h 3 INTERRUPT! 3 RAISE 7 n @ . .", " 1 7 ok"},
+		{"' h 3 INTERRUPT! 3 RAISE 3 RAISE n @ .", " 2 ok"},
+		{"' h 0 INTERRUPT! ' g 1 INTERRUPT! 1 RAISE 0 RAISE n @ .", " 11 ok"},
+		{"' h 3 INTERRUPT! 4 RAISE n @ .", " 0 ok"},
+		{"' h 3 INTERRUPT! DI 3 RAISE n @ . EI n @ .", " 0 1 ok"},
+		{": i 3 RAISE 5 0 DO n @ . LOOP ; ' i 2 INTERRUPT! ' h 3 INTERRUPT!\r" +
+			"2 RAISE n @ .", " 0 0 0 0 0 1 ok"},
+		{"' h 3 INTERRUPT! : l 3 RAISE 1 2 + . ; l n @ .", " 3 1 ok"},
+		{": e 1 THROW ; ' e 3 INTERRUPT! ' h 4 INTERRUPT! 3 RAISE\r" +
+			"4 RAISE n @ .", " 1 ok"},
+		{"' h 16 INTERRUPT!", "out of range"},
+		{": r RETI ; r", "RETI outside an interrupt"},
+	}
+	for _, v := range tests {
+		if out := raiseForth(def + v.src); !strings.Contains(out, v.good) {
+			t.Errorf("%q should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+// interrupts raised by another goroutine end a loop waiting for them
+func TestInterruptGoroutine(t *testing.T) {
+	o := new(bytes.Buffer)
+	f := New(strings.NewReader("VARIABLE n 0 n ! : h 1 n +! ; ' h 5 INTERRUPT!\r"+
+		"BEGIN n @ 3 = UNTIL .( done)\rBYE\r"), o)
+	stop := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				f.Interrupt(5)
+			}
+		}
+	}()
+	done := make(chan bool)
+	go func() {
+		f.Main()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the loop was not interrupted")
+	}
+	close(stop)
+	if !strings.Contains(o.String(), "done") {
+		t.Fatal("should print done but printed", o.String())
+	}
+}
@@ -0,0 +1,331 @@
+package eforth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+Locals live in a frame on the return stack.  (locals) pushes the old frame
+pointer LP, points LP at it and reserves a cell below it for every local,
+local i is at LP-2*(i+1).  (unlocal) drops the frame again and is compiled
+before every EXIT of a definition with locals.  CATCH restores LP, so a
+THROW out of words with locals leaves the frame of the catching word, QUIT
+and (included) are made to call this CATCH.
+
+The names are compiled after (locals) as a counted string so SEE can show
+them.
+*/
+func (f *Forth) addLocals() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"(locals)", "PLOCS", f._Locals, COMPO},
+		{"local@", "LOCAT", f._LocalAt, COMPO},
+		{"local!", "LOCST", f._LocalStore, COMPO},
+		{"(unlocal)", "UNLOC", f._Unlocal, COMPO},
+		{"lp@", "LPAT", f._LPat, 0},
+		{"lp!", "LPSTO", f._LPstore, 0},
+		{"{:", "BLOCS", f._BraceLocals, IMEDD | COMPO},
+		{"LOCALS|", "LOCSB", f._LocalsBar, IMEDD | COMPO},
+		{"(local?)", "LOCQ", f._LocalQ, 0},
+		{"(to)", "PTO", f._To, 0},
+		{"(endlocals)", "ENDLO", f._EndLocals, 0},
+		{"(nolocals)", "NOLOC", f._NoLocals, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Locals
+
+;   CATCH	( ca -- 0 | err# )
+;		Execute word at ca with an error frame, restore the locals frame after.
+
+		$COLON	5,'CATCH',CATCH
+		DW	LPAT,TOR,CATCH
+		DW	RFROM,LPSTO,EXIT
+
+;   TO		( w -- ; <string> )
+;		Store w in the local or value named next.
+
+		$COLON	IMEDD+2,'TO',TOO
+		DW	TOKEN,PTO,EXIT
+
+;   ;		( -- )
+;		Terminate a colon definition, dropping its locals frame.
+
+		$COLON	IMEDD+COMPO+1,';',SEMIS
+		DW	ENDLO
+		DW	COMPI,EXIT,LBRAC,OVERT,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+	f.callLatest("QUIT", "CATCH")
+	f.callLatest("(included)", "CATCH")
+}
+
+// (locals) ( x1 .. xn -- ) build a locals frame, n locals are initialized
+// from the stack and m are not, both are inline followed by the names
+func (f *Forth) _Locals() {
+	if !f.inMemory(f.IP, 2*CELLL+1) {
+		return
+	}
+	n := f.WordPtr(f.IP)
+	m := f.WordPtr(f.IP + CELLL)
+	c := uint16(f.Memory[f.IP+2*CELLL])
+	f.IP += 2*CELLL + (c+CELLL)/CELLL*CELLL
+	if int(f.RP) < CELLL*(1+int(n)+int(m)) {
+		f.throwMessage("return stack overflow")
+		return
+	}
+	f.RP -= CELLL
+	f.SetWordPtr(f.RP, f.LP)
+	f.LP = f.RP
+	f.RP -= CELLL * (n + m)
+	for i := n; i > 0; i-- {
+		f.SetWordPtr(f.LP-CELLL*i, f.Pop())
+	}
+	for i := n + 1; i <= n+m; i++ {
+		f.SetWordPtr(f.LP-CELLL*i, 0)
+	}
+	f.Next()
+}
+
+// local@ ( -- w ) push the local whose index is inline
+func (f *Forth) _LocalAt() {
+	i := f.WordPtr(f.IP)
+	f.IP += CELLL
+	a := f.LP - CELLL*(i+1)
+	if !f.inMemory(a, CELLL) {
+		return
+	}
+	f.Push(f.WordPtr(a))
+	f.Next()
+}
+
+// local! ( w -- ) store into the local whose index is inline
+func (f *Forth) _LocalStore() {
+	i := f.WordPtr(f.IP)
+	f.IP += CELLL
+	a := f.LP - CELLL*(i+1)
+	if !f.inMemory(a, CELLL) {
+		return
+	}
+	f.SetWordPtr(a, f.Pop())
+	f.Next()
+}
+
+// (unlocal) ( -- ) drop the locals frame
+func (f *Forth) _Unlocal() {
+	if !f.inMemory(f.LP, CELLL) {
+		return
+	}
+	f.RP = f.LP + CELLL
+	f.LP = f.WordPtr(f.LP)
+	f.Next()
+}
+
+// lp@ ( -- a ) push the locals frame pointer
+func (f *Forth) _LPat() {
+	f.Push(f.LP)
+	f.Next()
+}
+
+// lp! ( a -- ) set the locals frame pointer
+func (f *Forth) _LPstore() {
+	f.LP = f.Pop()
+	f.Next()
+}
+
+// THROW unless the input buffer is in Memory, a ! may have moved it out
+func (f *Forth) tibInMemory() bool {
+	ntib := f.userAddr("#TIB")
+	return f.inMemory(f.WordPtr(ntib+CELLL), int(f.WordPtr(ntib)))
+}
+
+/*
+Parse the next name from the input stream, return "" at the end of the line.
+Check the input buffer with tibInMemory first.
+*/
+func (f *Forth) parseName() string {
+	ntib := f.userAddr("#TIB")
+	in := f.userAddr(">IN")
+	tib := f.WordPtr(ntib + CELLL)
+	n := f.WordPtr(ntib)
+	i := f.WordPtr(in)
+	for i < n && f.Memory[tib+i] <= ' ' {
+		i += 1
+	}
+	j := i
+	for j < n && f.Memory[tib+j] > ' ' {
+		j += 1
+	}
+	s := string(f.Memory[tib+i : tib+j])
+	if j < n {
+		j += 1
+	}
+	f.SetWordPtr(in, j)
+	return s
+}
+
+// compile the start of a locals frame and make the names visible
+func (f *Forth) compileLocals(init, uninit []string) error {
+	names := append(append([]string{}, init...), uninit...)
+	for _, v := range names {
+		if len(v) > 31 {
+			return errors.New("local name too long")
+		}
+	}
+	s := strings.Join(names, " ")
+	if len(s) > 255 {
+		return errors.New("too many locals")
+	}
+	ca, _ := f.Addr("(locals)")
+	for _, v := range []uint16{ca, uint16(len(init)), uint16(len(uninit))} {
+		if err := f.comma(v); err != nil {
+			return err
+		}
+	}
+	a, err := f.allot(uint16(len(s) + 1))
+	if err != nil {
+		return err
+	}
+	f.Memory[a] = byte(len(s))
+	copy(f.Memory[a+1:], s)
+	f.locals = names
+	return nil
+}
+
+// {: ( -- ; <names> ) declare locals, {: a b | c -- d :} initializes a and b
+// from the stack and c to 0, the names after -- are a comment
+func (f *Forth) _BraceLocals() {
+	if !f.tibInMemory() {
+		return
+	}
+	if f.locals != nil {
+		f.throwMessage("locals already declared")
+		return
+	}
+	init, uninit := []string{}, []string{}
+	state := 0
+	for {
+		s := f.parseName()
+		switch {
+		case s == "":
+			f.throwMessage("missing :}")
+			return
+		case s == ":}":
+			if err := f.compileLocals(init, uninit); err != nil {
+				f.throwMessage(err.Error())
+				return
+			}
+			f.Next()
+			return
+		case s == "|" && state == 0:
+			state = 1
+		case s == "--":
+			state = 2
+		case state == 0:
+			init = append(init, s)
+		case state == 1:
+			uninit = append(uninit, s)
+		}
+	}
+}
+
+// LOCALS| ( -- ; <names> ) declare locals up to |, the last name is
+// initialized from the top of the stack
+func (f *Forth) _LocalsBar() {
+	if !f.tibInMemory() {
+		return
+	}
+	if f.locals != nil {
+		f.throwMessage("locals already declared")
+		return
+	}
+	init := []string{}
+	for {
+		s := f.parseName()
+		if s == "" {
+			f.throwMessage("missing |")
+			return
+		}
+		if s == "|" {
+			break
+		}
+		init = append([]string{s}, init...)
+	}
+	if err := f.compileLocals(init, nil); err != nil {
+		f.throwMessage(err.Error())
+		return
+	}
+	f.Next()
+}
+
+func (f *Forth) localIndex(name string) int {
+	for i, v := range f.locals {
+		if v == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// (local?) ( a -- a F | T ) compile a reference to the local named at a, or
+// dropping the locals frame before an EXIT
+func (f *Forth) _LocalQ() {
+	a := f.Pop()
+	name := f.countedString(a)
+	i := f.localIndex(name)
+	if f.locals == nil || i < 0 && name != "EXIT" {
+		f.Push(a)
+		f.Push(0)
+		f.Next()
+		return
+	}
+	var cells []uint16
+	if i >= 0 {
+		ca, _ := f.Addr("local@")
+		cells = []uint16{ca, uint16(i)}
+	} else {
+		unlocal, _ := f.Addr("(unlocal)")
+		exit, _ := f.Addr("EXIT")
+		cells = []uint16{unlocal, exit}
+	}
+	for _, v := range cells {
+		if err := f.comma(v); err != nil {
+			f.throwMessage(err.Error())
+			return
+		}
+	}
+	f.Push(asuint16(-1))
+	f.Next()
+}
+
+// (endlocals) ( -- ) compile dropping the locals frame at the end of a definition
+func (f *Forth) _EndLocals() {
+	if f.locals != nil {
+		ca, _ := f.Addr("(unlocal)")
+		if err := f.comma(ca); err != nil {
+			f.throwMessage(err.Error())
+			return
+		}
+		f.locals = nil
+	}
+	f.Next()
+}
+
+// (nolocals) ( -- ) forget the locals of a definition that was not finished
+func (f *Forth) _NoLocals() {
+	f.locals = nil
+	f.Next()
+}
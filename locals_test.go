@@ -0,0 +1,75 @@
+package eforth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocals(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{": l1 {: a b :} a b - ; 10 3 l1 .", " 7"},
+		{": l2 {: a b | c -- d :} a b + TO c c c * ; 2 3 l2 .", " 25"},
+		{": l3 {: x :} x 0 < IF x NEGATE EXIT THEN x ; -4 l3 . 5 l3 .", " 4 5"},
+		{": l4 LOCALS| a b | a b - ; 10 3 l4 .", " -7"},
+		{": in {: a :} a 1 + ; : out {: a b :} a in b in * a + ; 2 3 out .", " 14"},
+		{": l5 {: a :} 5 >R a R> + ; 1 l5 .", " 6"},
+		{": l6 {: a :} a ; 7 l6 . RP@ 7 l6 DROP RP@ - .", " 7 0"},
+		{": l7 {: a :} a ; : none 1 ; none .", " 1"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.HasPrefix(out, v.good) {
+			t.Errorf("%s should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestLocalsThrow(t *testing.T) {
+	src := ": bad 9 {: a :} a THROW ;\r: safe {: x :} x [ ' bad ] LITERAL CATCH . x ; 7 safe ."
+	if out, _ := runForth(src); !strings.Contains(out, " 9 7") {
+		t.Fatal("CATCH should restore the locals frame but printed", out)
+	}
+}
+
+func TestLocalsQuit(t *testing.T) {
+	src := "lp@ CONSTANT lp0\r: bad 9 {: a :} a THROW ;\rbad\rlp@ lp0 = .\rBYE\r"
+	o, f := NewForth(src)
+	f.Main()
+	if !strings.Contains(o.String(), "lp@ lp0 = . -1 ok") {
+		t.Fatal("QUIT should restore the locals frame after an error but printed", o)
+	}
+}
+
+func TestLocalsErrors(t *testing.T) {
+	o, f := NewForth(": nl {: a \r: ok 1 ; TO zz\rok . BYE\r")
+	f.Main()
+	t.Log(o.String())
+	for _, s := range []string{"missing :} ?", "zz ?", " 1"} {
+		if !strings.Contains(o.String(), s) {
+			t.Error("output should contain", s)
+		}
+	}
+}
+
+func TestSeeLocals(t *testing.T) {
+	out, _ := runForth(`: sl {: a b | c :} a b + TO c c 2 * ." hi" ; SEE sl`)
+	if !strings.Contains(out, `: sl {: a b | c :} a b + TO c c 2 * ." hi" ;`) {
+		t.Fatal("SEE should show the local names but printed", out)
+	}
+}
+
+// a locals count larger than the names in a damaged body shows all the names
+func TestSeeLocalsDamaged(t *testing.T) {
+	_, f := runForth(`: sl {: a b :} a b + ;`)
+	ca, _ := f.lookup("sl")
+	for _, in := range f.instructions(ca + 2*CELLL) {
+		if in.name == "(locals)" {
+			f.SetWordPtr(in.a+CELLL, 99)
+		}
+	}
+	if s := f.decompile(ca); !strings.Contains(s, ": sl {: a b :} a b + ;") {
+		t.Fatal("SEE should show the two names but showed", s)
+	}
+}
@@ -0,0 +1,90 @@
+package eforth
+
+import (
+	"fmt"
+)
+
+func (f *Forth) addLoops() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"(do)", "PDO", f._Do, COMPO},
+		{"(?do)", "PQDO", f._QDo, COMPO},
+		{"(loop)", "PLOOP", f._Loop, COMPO},
+		{"(+loop)", "PPLOO", f._PlusLoop, COMPO},
+		{"LEAVE", "LEAVE", f._Leave, COMPO},
+		{"UNLOOP", "UNLOO", f._Unloop, COMPO},
+		{"I", "II", f._I, COMPO},
+		{"J", "JJ", f._J, COMPO},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Counted loops and CASE
+
+;   DO		( -- A a )
+;		Start a DO-LOOP structure in a colon definition.
+
+		$COLON	IMEDD+2,'DO',DOO
+		DW	COMPI,PDO,HERE,DOLIT,0,COMMA
+		DW	HERE,EXIT
+
+;   ?DO		( -- A a )
+;		Start a DO-LOOP structure that is skipped if limit and index are equal.
+
+		$COLON	IMEDD+3,'?DO',QDOO
+		DW	COMPI,PQDO,HERE,DOLIT,0,COMMA
+		DW	HERE,EXIT
+
+;   LOOP	( A a -- )
+;		Terminate a DO-LOOP structure.
+
+		$COLON	IMEDD+4,'LOOP',LOOPP
+		DW	COMPI,PLOOP,COMMA,THENN,EXIT
+
+;   +LOOP	( A a -- )
+;		Terminate a DO-+LOOP structure.
+
+		$COLON	IMEDD+5,'+LOOP',PLUSL
+		DW	COMPI,PPLOO,COMMA,THENN,EXIT
+
+;   CASE	( -- 0 )
+;		Start a CASE-ENDCASE structure.
+
+		$COLON	IMEDD+4,'CASE',CASEE
+		DW	DOLIT,0,EXIT
+
+;   OF		( -- A )
+;		Start a clause taken if the selector equals tos.
+
+		$COLON	IMEDD+2,'OF',OFF
+		DW	COMPI,OVER,COMPI,EQUAL
+		DW	IFF,COMPI,DROP,EXIT
+
+;   ENDOF	( A -- A )
+;		Terminate an OF clause, continue after ENDCASE.
+
+		$COLON	IMEDD+5,'ENDOF',ENDOF
+		DW	ELSEE,EXIT
+
+;   ENDCASE	( 0 A .. A -- )
+;		Terminate a CASE-ENDCASE structure, dropping the selector.
+
+		$COLON	IMEDD+7,'ENDCASE',ENDCA
+		DW	COMPI,DROP
+ENDC1:		DW	QDUP			;resolve all the ENDOFs
+		DW	QBRAN,ENDC2
+		DW	THENN,BRAN,ENDC1
+ENDC2:		DW	EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
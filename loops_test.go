@@ -0,0 +1,56 @@
+package eforth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDoLoops(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{": l1 5 0 DO I . LOOP ; l1", " 0 1 2 3 4"},
+		{": l2 0 10 DO I . -3 +LOOP ; l2", " 10 7 4 1"},
+		{": l3 10 0 DO I . 4 +LOOP ; l3", " 0 4 8"},
+		{": l4 0 0 ?DO I . LOOP 7 . ; l4", " 7"},
+		{": l5 3 1 DO 3 1 DO J I * . LOOP LOOP ; l5", " 1 2 2 4"},
+		{": l6 10 0 DO I 3 = IF LEAVE THEN I . LOOP 9 . ; l6", " 0 1 2 9"},
+		{": l7 10 0 DO I 2 = IF UNLOOP EXIT THEN I . LOOP ; l7 8 .", " 0 1 8"},
+		{": l8 -1 -3 DO I . LOOP ; l8", " -3 -2"},
+		{": l9 0 0 DO I 2 = IF LEAVE THEN LOOP 5 . ; l9", " 5"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.HasPrefix(out, v.good) {
+			t.Errorf("%s should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestCase(t *testing.T) {
+	src := ": c CASE 0 OF 10 ENDOF 1 OF 11 ENDOF 99 SWAP ENDCASE ; 0 c . 1 c . 5 c ."
+	if out, _ := runForth(src); !strings.HasPrefix(out, " 10 11 99") {
+		t.Fatal("CASE should select the matching OF but printed", out)
+	}
+}
+
+func TestAddWordLoops(t *testing.T) {
+	o := new(bytes.Buffer)
+	f := New(strings.NewReader("0 5 0 sum . 32 kind . 5 kind DEPTH . BYE\r"), o)
+	if err := f.AddWord(": sum DO I + LOOP ;"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddWord(": kind CASE BL OF BL BL + ENDOF ENDCASE ;"); err != nil {
+		t.Fatal(err)
+	}
+	f.Main()
+	if !strings.Contains(o.String(), " 10 64 0") {
+		t.Fatal("host defined loops should run but printed", o.String())
+	}
+	for _, bad := range []string{": b1 LOOP ;", ": b2 DO ;", ": b3 OF ENDOF ;"} {
+		if err := f.AddWord(bad); err == nil {
+			t.Error(bad, "should not compile")
+		}
+	}
+}
@@ -8,45 +8,47 @@ import (
 
 func (f *Forth) addPrimitives() {
 	words := []struct {
-		word  string
-		m     fn
-		flags int
+		word   string
+		m      fn
+		flags  int
+		effect string // for CheckEffects, "" for words it can not follow
 	}{
-		{"BYE", f._BYE, 0},
-		{"CALL", f._Call, 0},
-		{"doLIST", f.doLIST, COMPO},
-		{"!IO", f._B_IO, 0},
-		{"?RX", f._Q_RX, 0},
-		{"TX!", f._B_TX, 0},
-		{"EXECUTE", f._Execute, 0},
-		{"doLIT", f.doLIT, COMPO},
-		{"EXIT", f._EXIT, 0},
-		{"next", f._Next, COMPO},
-		{"?branch", f._Q_branch, COMPO},
-		{"branch", f._Branch, COMPO},
-		{"!", f._Bang, 0},
-		{"@", f._At, 0},
-		{"C!", f._Cbang, 0},
-		{"C@", f._Cat, 0},
-		{"RP@", f._RPat, 0},
-		{"RP!", f._RPbang, COMPO},
-		{"R>", f._Rfrom, 0},
-		{"R@", f._Rat, 0},
-		{">R", f._Tor, COMPO},
-		{"DROP", f._Drop, 0},
-		{"DUP", f._Dup, 0},
-		{"SWAP", f._Swap, 0},
-		{"OVER", f._Over, 0},
-		{"SP@", f._Sp_at, 0},
-		{"SP!", f._Sp_bang, 0},
-		{"0<", f._Zless, 0},
-		{"AND", f._And, 0},
-		{"OR", f._Or, 0},
-		{"XOR", f._Xor, 0},
-		{"UM+", f._UMplus, 0},
+		{"BYE", f._BYE, 0, "( -- )"},
+		{"CALL", f._Call, 0, ""},
+		{"doLIST", f.doLIST, COMPO, ""},
+		{"!IO", f._B_IO, 0, "( -- )"},
+		{"?RX", f._Q_RX, 0, "( -- c T | F )"},
+		{"TX!", f._B_TX, 0, "( c -- )"},
+		{"EXECUTE", f._Execute, 0, ""},
+		{"doLIT", f.doLIT, COMPO, "( -- w )"},
+		{"EXIT", f._EXIT, 0, "( -- )"},
+		{"next", f._Next, COMPO, "( -- )"},
+		{"?branch", f._Q_branch, COMPO, "( f -- )"},
+		{"branch", f._Branch, COMPO, "( -- )"},
+		{"!", f._Bang, 0, "( w a -- )"},
+		{"@", f._At, 0, "( a -- w )"},
+		{"C!", f._Cbang, 0, "( c b -- )"},
+		{"C@", f._Cat, 0, "( b -- c )"},
+		{"RP@", f._RPat, 0, "( -- a )"},
+		{"RP!", f._RPbang, COMPO, ""},
+		{"R>", f._Rfrom, 0, "( -- w )"},
+		{"R@", f._Rat, 0, "( -- w )"},
+		{">R", f._Tor, COMPO, "( w -- )"},
+		{"DROP", f._Drop, 0, "( w -- )"},
+		{"DUP", f._Dup, 0, "( w -- w w )"},
+		{"SWAP", f._Swap, 0, "( w1 w2 -- w2 w1 )"},
+		{"OVER", f._Over, 0, "( w1 w2 -- w1 w2 w1 )"},
+		{"SP@", f._Sp_at, 0, "( -- a )"},
+		{"SP!", f._Sp_bang, 0, ""},
+		{"0<", f._Zless, 0, "( n -- t )"},
+		{"AND", f._And, 0, "( w w -- w )"},
+		{"OR", f._Or, 0, "( w w -- w )"},
+		{"XOR", f._Xor, 0, "( w w -- w )"},
+		{"UM+", f._UMplus, 0, "( w w -- w cy )"},
 	}
 	for _, v := range words {
 		f.AddPrim(v.word, v.m, v.flags)
+		f.declare(v.word, v.effect)
 	}
 }
 
@@ -58,6 +60,15 @@ func (f *Forth) _BYE() {
 	f.IP = 0xffff
 }
 
+/*
+An Input that tells without waiting whether a key was typed.  ?RX asks it
+when it is run instead of reading Input ahead in a goroutine, so keys are
+only taken when a word wants one.
+*/
+type KeyReader interface {
+	ReadKey() (c byte, ok bool)
+}
+
 /*
 CODE  !IO   ( -- )                  \ Initialize the serial I/O devices.
       $Next
@@ -65,20 +76,35 @@ CODE  !IO   ( -- )                  \ Initialize the serial I/O devices.
 // initialize IO
 func (f *Forth) _B_IO() {
 	//in := f.b_input
+	if f.rxquit != nil {
+		close(f.rxquit) // stop the reader of an earlier !IO
+		f.rxquit = nil
+	}
+	if _, ok := f.Input.(KeyReader); ok {
+		f.rxchan = nil
+		f.Next()
+		return
+	}
 	c := make(chan uint16)
+	quit := make(chan struct{})
+	send := func(v uint16) bool {
+		select {
+		case c <- v:
+			return true
+		case <-quit:
+			return false
+		}
+	}
 	go func() {
 		buf := make([]byte, 1)
-	forloop:
 		for {
 			if f.Input != nil {
 				_, err := f.Input.Read(buf)
 				//b, err := in.ReadByte()
 				if err != nil {
 					//            fmt.Println("could not read Byte", err)
-					c <- 0
-					if err == io.EOF {
-						fmt.Println("break for io.EOF")
-						break forloop
+					if !send(0) || err == io.EOF {
+						return
 					}
 				} else {
 					b := buf[0]
@@ -86,17 +112,17 @@ func (f *Forth) _B_IO() {
 						b = 13
 					}
 					//            fmt.Println("\nRX:", b, string(b))
-					c <- uint16(b)
-					c <- asuint16(-1)
+					if !send(uint16(b)) || !send(asuint16(-1)) {
+						return
+					}
 				}
-			} else {
-				c <- 0
+			} else if !send(0) {
+				return
 			}
-
 		}
-		fmt.Println("exiting goroutine")
 	}()
 	f.rxchan = c
+	f.rxquit = quit
 	f.Next()
 }
 
@@ -107,7 +133,9 @@ CODE  EXECUTE     ( ca -- )         \ _Execute the word at ca.
 */
 func (f *Forth) _Execute() {
 	bx := f.Pop()
-	f.WP = bx
+	if f.inMemory(bx, CELLL) {
+		f.WP = bx
+	}
 }
 
 /*
@@ -130,26 +158,69 @@ QRX2: PUSH  BX                      \ save character
 QRX3: PUSH  BX
       $Next
 */
+// what ?RX found
+const (
+	rxNone = iota // no key yet
+	rxKey         // a key
+	rxEnd         // the reader of Input could not read
+)
+
 // RX may need to be non-blocking receive
 // returns either false or char true
 func (f *Forth) _Q_RX() {
+	f.polls++
+	var b uint16
+	var what int
+	if f.replay != nil {
+		if f.replay.done() {
+			f._BYE() // the session recorded ended here
+			return
+		}
+		b, what = f.replayRX()
+	} else {
+		b, what = f.rx()
+		f.recordRX(b, what)
+	}
+	switch what {
+	case rxKey:
+		f.Push(b)
+		f.Push(asuint16(-1))
+	case rxEnd:
+		f.Push(0)
+	default:
+		f.Push(0)
+		// let the other tasks run while waiting
+		if !f.switchTask() && f.replay == nil {
+			time.Sleep(1 * time.Millisecond)
+		}
+	}
+	f.Next()
+}
+
+// return the key typed, if there is one, and what was found
+func (f *Forth) rx() (uint16, int) {
+	if k, ok := f.Input.(KeyReader); ok && f.rxchan == nil {
+		if b, ok := k.ReadKey(); ok {
+			if b == 10 {
+				b = 13
+			}
+			return uint16(b), rxKey
+		}
+	}
 	c := f.rxchan
 	select {
 	case res := <-c:
 		if res == 0 {
-			f.Push(0)
-		} else {
-			f.Push(res)
-			f.Push(<-c)
+			return 0, rxEnd
 		}
+		<-c
+		return res, rxKey
 	// I really don't like this solution that much
 	// but at least this is a solution.
 	// evidently unix likes the philosophy of blocking io too
 	default:
-		time.Sleep(1 * time.Millisecond)
-		f.Push(0)
+		return 0, rxNone
 	}
-	f.Next()
 }
 
 /*
@@ -220,6 +291,9 @@ func (f *Forth) doLIST() {
    CALL ADDR  ; for example
 */
 func (f *Forth) _Call() {
+	if !f.inMemory(f.WP, 2*CELLL) {
+		return
+	}
 	f.Push(f.WP + 4)
 	f.WP = f.WordPtr(f.WP + 2) // move WP over two and down one to the address of doLIST
 }
@@ -267,6 +341,113 @@ func (f *Forth) _Next() {
 	f.Next()
 }
 
+/*
+(do)  ( limit index -- )            \ Start a DO loop.  The inline
+                                    \ address is where LEAVE goes, it
+                                    \ is kept on the return stack
+                                    \ under the limit and index.
+*/
+func (f *Forth) _Do() {
+	index := f.Pop()
+	limit := f.Pop()
+	f.RP = f.RP - 3*CELLL
+	f.SetWordPtr(f.RP+2*CELLL, f.WordPtr(f.IP))
+	f.SetWordPtr(f.RP+CELLL, limit)
+	f.SetWordPtr(f.RP, index)
+	f.IP = f.IP + CELLL
+	f.Next()
+}
+
+/*
+(?do)  ( limit index -- )           \ Start a DO loop or skip it to the
+                                    \ inline address if limit = index.
+*/
+func (f *Forth) _QDo() {
+	if f.WordPtr(f.SP) == f.WordPtr(f.SP+CELLL) {
+		f.SP = f.SP + 2*CELLL
+		f.IP = f.WordPtr(f.IP)
+		f.Next()
+		return
+	}
+	f._Do()
+}
+
+// add n to the loop index and return true if it crossed the boundary between
+// limit-1 and limit, in which case the loop parameters are dropped
+func (f *Forth) loopStep(n int16) bool {
+	index := f.WordPtr(f.RP)
+	limit := f.WordPtr(f.RP + CELLL)
+	o := asint16(index - limit)
+	o2 := asint16(uint16(o) + uint16(n))
+	if n >= 0 && o < 0 && o2 >= 0 || n < 0 && o >= 0 && o2 < 0 {
+		f.RP = f.RP + 3*CELLL
+		return true
+	}
+	f.SetWordPtr(f.RP, index+uint16(n))
+	return false
+}
+
+/*
+(loop)  ( -- )                      \ Increment the index and branch back
+                                    \ to the inline address unless the
+                                    \ loop is done.
+*/
+func (f *Forth) _Loop() {
+	if f.loopStep(1) {
+		f.IP = f.IP + CELLL
+	} else {
+		f.IP = f.WordPtr(f.IP)
+	}
+	f.Next()
+}
+
+/*
+(+loop)  ( n -- )                   \ Add n to the index and branch back
+                                    \ unless it crossed the limit.
+*/
+func (f *Forth) _PlusLoop() {
+	if f.loopStep(asint16(f.Pop())) {
+		f.IP = f.IP + CELLL
+	} else {
+		f.IP = f.WordPtr(f.IP)
+	}
+	f.Next()
+}
+
+/*
+LEAVE  ( -- )                       \ Drop the loop parameters and go to
+                                    \ the end of the loop.
+*/
+func (f *Forth) _Leave() {
+	f.IP = f.WordPtr(f.RP + 2*CELLL)
+	f.RP = f.RP + 3*CELLL
+	f.Next()
+}
+
+/*
+UNLOOP  ( -- )                      \ Drop the loop parameters.
+*/
+func (f *Forth) _Unloop() {
+	f.RP = f.RP + 3*CELLL
+	f.Next()
+}
+
+/*
+I  ( -- n )                         \ Index of the innermost loop.
+*/
+func (f *Forth) _I() {
+	f.Push(f.WordPtr(f.RP))
+	f.Next()
+}
+
+/*
+J  ( -- n )                         \ Index of the next outer loop.
+*/
+func (f *Forth) _J() {
+	f.Push(f.WordPtr(f.RP + 3*CELLL))
+	f.Next()
+}
+
 /*
 CODE  ?branch     ( f -- )          \ _Branch if flag is zero.
       POP   BX                      \ pop flag
@@ -306,6 +487,9 @@ CODE  !     ( w a -- )              \ Pop the data stack to memory.
 func (f *Forth) _Bang() {
 	a := f.Pop()
 	v := f.Pop()
+	if !f.inMemory(a, CELLL) {
+		return
+	}
 	f.SetWordPtr(a, v)
 	f.Next()
 }
@@ -318,6 +502,9 @@ CODE  @     ( a -- w )              \ Push memory location to data stack.
 */
 func (f *Forth) _At() {
 	bx := f.Pop()
+	if !f.inMemory(bx, CELLL) {
+		return
+	}
 	v := f.WordPtr(bx)
 	f.Push(v)
 	f.Next()
@@ -333,6 +520,9 @@ CODE  C!    ( c b -- )              \ Pop data stack to byte memory.
 func (f *Forth) _Cbang() {
 	bx := f.Pop()
 	ax := f.Pop()
+	if !f.inMemory(bx, 1) {
+		return
+	}
 	f.Memory[bx] = f.RegLower(ax)
 	f.Next()
 }
@@ -347,8 +537,10 @@ CODE  C@    ( b -- c )              \ Push byte memory content on data stack.
 */
 func (f *Forth) _Cat() {
 	bx := f.Pop()
-	ax := f.WordPtr(bx)
-	ax = 0x00ff & ax
+	if !f.inMemory(bx, 1) {
+		return
+	}
+	ax := uint16(f.Memory[bx])
 	f.Push(ax)
 	f.Next()
 }
@@ -76,3 +76,44 @@ func TestTx(t *testing.T) {
 		t.Fatal("val should be c and not", val)
 	}
 }
+
+// keys polled from a slice, the rest is left where it was
+type pollKeys struct {
+	keys  []byte
+	polls int
+}
+
+func (p *pollKeys) ReadKey() (byte, bool) {
+	p.polls++
+	if len(p.keys) == 0 {
+		return 0, false
+	}
+	b := p.keys[0]
+	p.keys = p.keys[1:]
+	return b, true
+}
+
+func (p *pollKeys) Read(b []byte) (int, error) {
+	t := []byte("KeyReader Read")
+	return copy(b, t), nil
+}
+
+func TestKeyReader(t *testing.T) {
+	k := &pollKeys{keys: []byte("a\nb")}
+	f := New(k, nil)
+	f._B_IO()
+	for _, want := range []uint16{'a', 13} {
+		f._Q_RX()
+		if flag, c := f.Pop(), f.Pop(); flag == 0 || c != want {
+			t.Fatal("?RX should return", want, "but returned", c, flag)
+		}
+	}
+	if string(k.keys) != "b" {
+		t.Fatal("keys should only be read when asked but", string(k.keys), "is left")
+	}
+	k.keys = nil
+	f._Q_RX()
+	if flag := f.Pop(); flag != 0 {
+		t.Fatal("?RX should return false without a key but returned", flag)
+	}
+}
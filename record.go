@@ -0,0 +1,191 @@
+package eforth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/*
+A record is a line of text for every event that depends on something
+outside the Forth, numbered by the ?RX or the Step it happened in:
+
+	eForth record 1
+	k poll c	?RX number poll returned key c
+	e poll		?RX number poll found the reader of Input failed
+	i step mask	the interrupts in mask were raised before Step number step
+	t time		the clock read time, in RFC 3339
+
+Every other ?RX found no key.
+*/
+const recordMagic = "eForth record 1"
+
+// writes the record, see Record
+type recorder struct {
+	errWriter
+	buf *bufio.Writer
+}
+
+/*
+Write to w what the Forth reads from Input, the interrupts raised and the
+times read from the Clock, so Replay can make it run the same Steps again.
+Record(nil) stops recording, the record is also flushed for every key and
+when Main returns.  Returns the first error writing the record.
+*/
+func (f *Forth) Record(w io.Writer) error {
+	err := f.flushRecord()
+	f.record = nil
+	if w != nil {
+		buf := bufio.NewWriter(w)
+		f.record = &recorder{errWriter{w: buf}, buf}
+		f.record.printf("%s\n", recordMagic)
+	}
+	return err
+}
+
+// write what is buffered and return the first error writing the record
+func (f *Forth) flushRecord() error {
+	r := f.record
+	if r == nil {
+		return nil
+	}
+	if r.err == nil {
+		r.err = r.buf.Flush()
+	}
+	return r.err
+}
+
+func (f *Forth) recordRX(b uint16, what int) {
+	if f.record == nil {
+		return
+	}
+	switch what {
+	case rxKey:
+		f.record.printf("k %d %d\n", f.polls, b)
+		f.flushRecord()
+	case rxEnd:
+		f.record.printf("e %d\n", f.polls)
+	}
+}
+
+func (f *Forth) recordInterrupts(mask uint32) {
+	if f.record != nil {
+		f.record.printf("i %d %d\n", f.steps, mask)
+	}
+}
+
+func (f *Forth) recordTime(t time.Time) {
+	if f.record != nil {
+		f.record.printf("t %s\n", t.Format(time.RFC3339Nano))
+	}
+}
+
+// an event of a record
+type event struct {
+	n    uint64 // ?RX or Step it happened in
+	what int    // rxKey or rxEnd for ?RX
+	v    uint32 // key or interrupts
+}
+
+// the events of a record not replayed yet, see Replay
+type replayer struct {
+	rx    []event
+	irq   []event
+	times []time.Time
+}
+
+/*
+Run the events written by Record again instead of reading Input, taking the
+interrupts raised and reading the Clock.  The Forth has to be set up as the
+one recorded was, with the same words bound, files loaded and image, then
+its Main runs the same Steps and writes the same output.  MS does not wait
+and once every key and interrupt recorded is replayed the next ?RX ends
+Main like BYE.
+*/
+func (f *Forth) Replay(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	if !s.Scan() || s.Text() != recordMagic {
+		return errors.New("not an eForth record")
+	}
+	p := &replayer{}
+	for line := 1; s.Scan(); line++ {
+		var e event
+		var err error
+		t := s.Text()
+		switch {
+		case strings.HasPrefix(t, "k "):
+			e.what = rxKey
+			_, err = fmt.Sscanf(t, "k %d %d", &e.n, &e.v)
+			p.rx = append(p.rx, e)
+		case strings.HasPrefix(t, "e "):
+			e.what = rxEnd
+			_, err = fmt.Sscanf(t, "e %d", &e.n)
+			p.rx = append(p.rx, e)
+		case strings.HasPrefix(t, "i "):
+			_, err = fmt.Sscanf(t, "i %d %d", &e.n, &e.v)
+			p.irq = append(p.irq, e)
+		case strings.HasPrefix(t, "t "):
+			var tm time.Time
+			tm, err = time.Parse(time.RFC3339Nano, t[2:])
+			p.times = append(p.times, tm)
+		default:
+			err = errors.New("unknown event")
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf("record line %d: %v", line+1, err))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	f.replay = p
+	f.Input = noKeys{}
+	return nil
+}
+
+// an Input without keys
+type noKeys struct{}
+
+func (noKeys) ReadKey() (byte, bool)      { return 0, false }
+func (noKeys) Read(p []byte) (int, error) { return 0, io.EOF }
+
+// has every key and interrupt recorded been replayed
+func (p *replayer) done() bool {
+	return len(p.rx) == 0 && len(p.irq) == 0
+}
+
+// return what the ?RX being run found when it was recorded
+func (f *Forth) replayRX() (uint16, int) {
+	p := f.replay
+	if len(p.rx) == 0 || p.rx[0].n != f.polls {
+		return 0, rxNone
+	}
+	e := p.rx[0]
+	p.rx = p.rx[1:]
+	return uint16(e.v), e.what
+}
+
+// latch the interrupts recorded for the Step being run
+func (f *Forth) replayInterrupts() {
+	p := f.replay
+	if len(p.irq) > 0 && p.irq[0].n == f.steps {
+		f.irq |= p.irq[0].v
+		p.irq = p.irq[1:]
+	}
+}
+
+// return the next time recorded, the last one again after it
+func (f *Forth) replayTime() time.Time {
+	p := f.replay
+	if len(p.times) == 0 {
+		return time.Time{}
+	}
+	t := p.times[0]
+	if len(p.times) > 1 {
+		p.times = p.times[1:]
+	}
+	return t
+}
@@ -0,0 +1,112 @@
+package eforth
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	lines := []string{
+		"VARIABLE n 0 n ! VARIABLE m 0 m ! : h 1 n +! ; : g 1 m +! ;\r",
+		"' h 2 INTERRUPT! ' g 5 3 TIMER : w BEGIN 20 n @ < UNTIL ;\r",
+		"w m @ . n @ . TICKS . TIME&DATE . . . . . .\r",
+		"BYE\r",
+	}
+	r, w := io.Pipe()
+	go func() {
+		for _, l := range lines {
+			time.Sleep(5 * time.Millisecond)
+			w.Write([]byte(l))
+		}
+	}()
+	o, rec := new(bytes.Buffer), new(bytes.Buffer)
+	f := New(r, o)
+	f.Record(rec)
+	stop := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				f.Interrupt(2)
+			}
+		}
+	}()
+	f.Main()
+	close(stop)
+	if !strings.Contains(o.String(), "TIME&DATE . . . . . .") || strings.Contains(o.String(), " ? ") {
+		t.Fatal("the session did not run, it printed", o.String())
+	}
+	if n := strings.Count(rec.String(), "\nt "); n != 3 {
+		t.Errorf("TIMER, TICKS and TIME&DATE should read the clock but it was recorded %d times", n)
+	}
+
+	o2 := new(bytes.Buffer)
+	g := New(nil, o2)
+	if err := g.Replay(bytes.NewReader(rec.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		g.Main()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the replay did not end, it printed", o2.String())
+	}
+	if o2.String() != o.String() || g.steps != f.steps {
+		t.Errorf("printed %q in %d steps, the replay printed %q in %d steps",
+			o.String(), f.steps, o2.String(), g.steps)
+	}
+}
+
+func TestReplayWithoutBye(t *testing.T) {
+	rec := new(bytes.Buffer)
+	f := New(&pollKeys{keys: []byte("1 2 + .\rBYE\r")}, new(bytes.Buffer))
+	f.Record(rec)
+	f.Main()
+	lines := strings.SplitAfter(rec.String(), "\n")
+	cut := strings.Join(lines[:len(lines)-5], "") // the keys of BYE\r
+	o := new(bytes.Buffer)
+	g := New(nil, o)
+	if err := g.Replay(strings.NewReader(cut)); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		g.Main()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the replay did not end after the last key")
+	}
+	if !strings.Contains(o.String(), "1 2 + . 3 ok") {
+		t.Error("the replay should run the line recorded but printed", o)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	tests := []struct {
+		rec string
+		err string
+	}{
+		{"", "not an eForth record"},
+		{"eForth record 1\nk 1 65\nx 2\n", "record line 3: unknown event"},
+		{"eForth record 1\nt yesterday\n", "record line 2: "},
+		{"eForth record 1\ni 5\n", "record line 2: "},
+	}
+	for _, v := range tests {
+		err := New(nil, nil).Replay(strings.NewReader(v.rec))
+		if err == nil || !strings.HasPrefix(err.Error(), v.err) {
+			t.Errorf("replaying %q should fail with %q but returned %v", v.rec, v.err, err)
+		}
+	}
+}
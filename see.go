@@ -0,0 +1,222 @@
+package eforth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// words compiled with an inline string and the word that compiles them
+var inlineStrings = map[string]string{
+	`$"|`:    `$"`,
+	`."|`:    `."`,
+	`abort"`: `ABORT"`,
+	`S"|`:    `S"`,
+}
+
+// words compiled with an inline branch address
+var branches = map[string]bool{
+	"branch": true, "?branch": true, "next": true,
+	"(do)": true, "(?do)": true, "(loop)": true, "(+loop)": true,
+}
+
+func (f *Forth) addSee() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"(see)", "PSEE", f._See, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Decompiler
+
+;   SEE		( -- ; <string> )
+;		Display the source of the word named next.
+
+		$COLON	3,'SEE',SEE
+		DW	TICK,PSEE,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+/*
+Return the name of the word at code address ca and its lexicon bits from
+the context vocabulary, before boot the name it was defined with.
+*/
+func (f *Forth) nameOf(ca uint16) (name string, flags byte, ok bool) {
+	if !f.booted {
+		name, ok = f.addr2word[ca] // there is no context vocabulary yet
+		return name, 0, ok
+	}
+	na := f.WordPtr(f.WordPtr(f.userAddr("CONTEXT")))
+	for i := 0; na != 0 && i < EM/CELLL; i++ {
+		if f.WordPtr(na-2*CELLL) == ca {
+			l := f.Memory[na]
+			n := uint16(l & 0x1F)
+			return string(f.Memory[na+1 : na+1+n]), l &^ 0x1F, true
+		}
+		na = f.WordPtr(na - CELLL)
+	}
+	return "", 0, false
+}
+
+// format a cell as a signed number in the current BASE
+func (f *Forth) formatCell(v uint16) string {
+	base := int(f.WordPtr(f.userAddr("BASE")))
+	if base < 2 || base > 36 {
+		base = 10
+	}
+	return strings.ToUpper(strconv.FormatInt(int64(asint16(v)), base))
+}
+
+/*
+Return the source of the word at ca as well as it can be recovered.
+*/
+func (f *Forth) decompile(ca uint16) string {
+	name, flags, _ := f.nameOf(ca)
+	dolist, _ := f.Addr("doLIST")
+	if f.WordPtr(ca) != CALLL || f.WordPtr(ca+CELLL) != dolist {
+		if c := f.StackComment(name); c != "" {
+			return "CODE " + name + " " + c
+		}
+		return "CODE " + name
+	}
+	body := ca + 2*CELLL
+	first, _, _ := f.nameOf(f.WordPtr(body))
+	switch first {
+	case "doVAR":
+		return "CREATE " + name
+	case "doUSER":
+		return "USER " + name
+	case "doVOC":
+		return "VOCABULARY " + name
+	case "doCON":
+		return f.formatCell(f.WordPtr(body+CELLL)) + " CONSTANT " + name
+	case "doVAL":
+		return f.formatCell(f.WordPtr(body+CELLL)) + " VALUE " + name
+	case "doDEFER":
+		action, _, ok := f.nameOf(f.WordPtr(body + CELLL))
+		if !ok || action == "(defer)" {
+			return "DEFER " + name
+		}
+		return "DEFER " + name + " ' " + action + " IS " + name
+	}
+	out := []string{":", name}
+	for _, in := range f.instructions(body) {
+		if in.text != "" {
+			out = append(out, in.text)
+		}
+		if in.text == ";" && flags&IMEDD != 0 {
+			out = append(out, "IMMEDIATE")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// a word compiled in a colon definition with its inline data
+type instr struct {
+	a    uint16 // address of the cell
+	name string // of the word, "" for a cell that is not one
+	text string // as SEE shows it, "" for nothing
+}
+
+/*
+Return the words compiled in the colon definition whose body starts at
+body, up to the EXIT after the last address a branch goes to.
+*/
+func (f *Forth) instructions(body uint16) []instr {
+	var is []instr
+	var locals []string
+	var end uint16 // the last address a branch goes to
+	a := body
+	for int(a) < EM-CELLL && a < body+EM/4 {
+		w := f.WordPtr(a)
+		in := instr{a: a}
+		a += CELLL
+		wn, _, ok := f.nameOf(w)
+		in.name = wn
+		switch {
+		case !ok:
+			in.text = f.formatCell(w)
+		case wn == "doLIT":
+			in.text = f.formatCell(f.WordPtr(a))
+			a += CELLL
+		case branches[wn]:
+			t := f.WordPtr(a)
+			if t > end {
+				end = t
+			}
+			in.text = fmt.Sprintf("%s %X", wn, t)
+			a += CELLL
+			if next, _, _ := f.nameOf(f.WordPtr(a)); wn == "branch" && a > end && next != "EXIT" {
+				return append(is, in) // nothing after it runs
+			}
+		case inlineStrings[wn] != "":
+			in.text = inlineStrings[wn] + " " + f.countedString(a) + `"`
+			a += (uint16(f.Memory[a]) + CELLL) / CELLL * CELLL
+		case wn == "COMPILE":
+			n, _, ok := f.nameOf(f.WordPtr(a))
+			if !ok {
+				n = f.formatCell(f.WordPtr(a))
+			}
+			in.text = "COMPILE " + n
+			a += CELLL
+		case wn == "doFLIT":
+			in.text = formatFloat(f.float(a), 's', f.precision)
+			a += FLOATT
+		case wn == "(locals)":
+			if int(a)+2*CELLL >= EM {
+				return append(is, in)
+			}
+			n := int(f.WordPtr(a))
+			locals = strings.Fields(f.countedString(a + 2*CELLL))
+			if n > len(locals) {
+				n = len(locals) // a damaged or hand-written body
+			}
+			decl := append([]string{"{:"}, locals[:n]...)
+			if len(locals) > n {
+				decl = append(append(decl, "|"), locals[n:]...)
+			}
+			in.text = strings.Join(append(decl, ":}"), " ")
+			a += 2*CELLL + (uint16(f.Memory[a+2*CELLL])+CELLL)/CELLL*CELLL
+		case wn == "local@" || wn == "local!" || wn == "local+!":
+			i := int(f.WordPtr(a))
+			a += CELLL
+			l := "?"
+			if i < len(locals) {
+				l = locals[i]
+			}
+			in.text = map[string]string{"local@": "", "local!": "TO ", "local+!": "+TO "}[wn] + l
+		case wn == "value!" || wn == "value+!":
+			v, _, _ := f.nameOf(f.WordPtr(a))
+			a += CELLL
+			in.text = map[string]string{"value!": "TO", "value+!": "+TO"}[wn] + " " + v
+		case wn == "(unlocal)":
+			// part of EXIT or ;
+		case wn == "EXIT" && a > end:
+			in.text = ";"
+			return append(is, in)
+		default:
+			in.text = wn
+		}
+		is = append(is, in)
+	}
+	return is
+}
+
+// (see) ( ca -- ) display the decompiled word at ca
+func (f *Forth) _See() {
+	ca := f.Pop()
+	f.typeString("\r\n" + f.decompile(ca))
+	f.Next()
+}
@@ -0,0 +1,26 @@
+package eforth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSee(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{": sq DUP * ; SEE sq", ": sq DUP * ;"},
+		{`: sw 0< IF -1 ELSE 1 THEN ; SEE sw`, ": sw 0< ?branch"},
+		{`: st S" abc" TYPE $" x" DROP ; SEE st`, `: st S" abc" TYPE $" x" DROP ;`},
+		{": fl 1.5E0 F. ; SEE fl", ": fl 1.5E0 F. ;"},
+		{": im 1 ; IMMEDIATE SEE im", ": im 1 ; IMMEDIATE"},
+		{"VARIABLE v SEE v", "CREATE v"},
+		{"SEE DUP", "CODE DUP"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.Contains(out, v.good) {
+			t.Errorf("%s should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package eforth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const STRBUFF = 512 // size of the ring buffer for transient strings
+
+func (f *Forth) addString() {
+	f.strbuf, _ = f.allot(STRBUFF)
+
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"COMPARE", "COMPA", f._Compare, 0},
+		{"SEARCH", "SEARC", f._Search, 0},
+		{"CMOVE>", "CMOVU", f._CmoveUp, 0},
+		{`parse\"`, "PSBSQ", f._ParseEscaped, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Strings
+
+;   /STRING	( b u n -- b+n u-n )
+;		Remove n characters from the start of a string.
+
+		$COLON	7,'/STRING',SLASS
+		DW	DUPP,TOR,SUBB
+		DW	SWAP,RFROM,PLUS,SWAP,EXIT
+
+;   BLANK	( b u -- )
+;		Fill u bytes from b with blanks.
+
+		$COLON	5,'BLANK',BLNKK
+		DW	BLANK,FILL,EXIT
+
+;   S"|		( -- b u )
+;		Run time routine compiled by SLITERAL. Return a compiled string.
+
+		$COLON	COMPO+3,'S"|',SQUOP
+		DW	DOSTR,COUNT,EXIT
+
+;   SLITERAL	( b u -- )
+;		Compile a string to be returned at run time.
+
+		$COLON	IMEDD+8,'SLITERAL',SLITE
+		DW	COMPI,SQUOP
+		DW	HERE,PACKS		;move string to code dictionary
+		DW	COUNT,PLUS,ALGND	;calculate aligned end of string
+		DW	CP,STORE,EXIT		;adjust the code pointer
+
+;   ?compile	( b u -- b u | )
+;		Compile the string as a literal unless interpreting.
+
+		$COLON	8,'?compile',QCOMS
+		DW	TEVAL,AT,DOLIT,INTER,EQUAL
+		DW	QBRAN,QCOM1
+		DW	EXIT
+QCOM1:		DW	SLITE,EXIT
+
+;   S"		( -- b u | ; <string> )
+;		Return the string up to next " , compiled as a literal if compiling.
+
+		$COLON	IMEDD+2,'S"',SQUOT
+		DW	DOLIT,'"',PARSE,QCOMS,EXIT
+
+;   S\"		( -- b u | ; <string> )
+;		Like S" with \ escapes in the string.
+
+		$COLON	IMEDD+3,'S\"',SBSQU
+		DW	PSBSQ,QCOMS,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+/*
+Return the string of u bytes at b.
+*/
+func (f *Forth) StringAt(b, u uint16) string {
+	if int(b)+int(u) > EM {
+		return ""
+	}
+	return string(f.Memory[b : b+u])
+}
+
+/*
+Pop a string as the address and length pair b u off of the data stack.
+*/
+func (f *Forth) PopString() string {
+	u := f.Pop()
+	b := f.Pop()
+	return f.StringAt(b, u)
+}
+
+/*
+Copy s to the transient string buffer and push its address and length.  The
+buffer is reused in turn, so the string is only valid until enough other
+strings are pushed.
+*/
+func (f *Forth) PushString(s string) error {
+	b, err := f.transient(s)
+	if err != nil {
+		return err
+	}
+	f.Push(b)
+	f.Push(uint16(len(s)))
+	return nil
+}
+
+// copy s to the next free place in the string ring buffer
+func (f *Forth) transient(s string) (uint16, error) {
+	if len(s) > STRBUFF {
+		return 0, errors.New(fmt.Sprintf("string longer than %d", STRBUFF))
+	}
+	if f.strnext+len(s) > STRBUFF {
+		f.strnext = 0
+	}
+	b := f.strbuf + uint16(f.strnext)
+	copy(f.Memory[b:], s)
+	f.strnext += len(s)
+	return b, nil
+}
+
+// COMPARE ( b1 u1 b2 u2 -- n ) compare two strings, n is -1, 0 or 1
+func (f *Forth) _Compare() {
+	u2, b2 := f.Pop(), f.Pop()
+	u1, b1 := f.Pop(), f.Pop()
+	if !f.inMemory(b1, int(u1)) || !f.inMemory(b2, int(u2)) {
+		return
+	}
+	s1, s2 := f.StringAt(b1, u1), f.StringAt(b2, u2)
+	f.Push(asuint16(int16(bytes.Compare([]byte(s1), []byte(s2)))))
+	f.Next()
+}
+
+// SEARCH ( b1 u1 b2 u2 -- b3 u3 t ) search the first string for the second,
+// return the rest of the first string from the match or it unchanged and false
+func (f *Forth) _Search() {
+	u2, b2 := f.Pop(), f.Pop()
+	u1 := f.Pop()
+	b1 := f.Pop()
+	if !f.inMemory(b1, int(u1)) || !f.inMemory(b2, int(u2)) {
+		return
+	}
+	s2 := f.StringAt(b2, u2)
+	i := bytes.Index(f.Memory[b1:b1+u1], []byte(s2))
+	if i < 0 {
+		f.Push(b1)
+		f.Push(u1)
+		f.Push(0)
+	} else {
+		f.Push(b1 + uint16(i))
+		f.Push(u1 - uint16(i))
+		f.Push(asuint16(-1))
+	}
+	f.Next()
+}
+
+// CMOVE> ( b1 b2 u -- ) copy u bytes from b1 to b2 starting at the high end
+func (f *Forth) _CmoveUp() {
+	u := int(f.Pop())
+	b2 := int(f.Pop())
+	b1 := int(f.Pop())
+	if !f.inMemory(uint16(b1), u) || !f.inMemory(uint16(b2), u) {
+		return
+	}
+	for i := u - 1; i >= 0; i-- {
+		f.Memory[b2+i] = f.Memory[b1+i]
+	}
+	f.Next()
+}
+
+var escapes = map[byte]string{
+	'a': "\a", 'b': "\b", 'e': "\x1b", 'f': "\f", 'l': "\n",
+	'm': "\r\n", 'n': "\n", 'q': `"`, 'r': "\r", 't': "\t",
+	'v': "\v", 'z': "\x00", '"': `"`, '\\': `\`,
+}
+
+/*
+Translate the escapes of S\" in s up to the closing ", returns the string and
+the number of characters of s used.
+*/
+func unescape(s string) (string, int, error) {
+	var b bytes.Buffer
+	i := 0
+	for i < len(s) && s[i] != '"' {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			i += 1
+			continue
+		}
+		if i+1 == len(s) {
+			return "", i, errors.New(`unfinished \ escape`)
+		}
+		c := s[i+1]
+		i += 2
+		if c == 'x' {
+			if i+2 > len(s) {
+				return "", i, errors.New(`bad \x escape`)
+			}
+			n, err := strconv.ParseUint(s[i:i+2], 16, 8)
+			if err != nil {
+				return "", i, errors.New(`bad \x escape`)
+			}
+			b.WriteByte(byte(n))
+			i += 2
+			continue
+		}
+		e, ok := escapes[c]
+		if !ok {
+			return "", i, errors.New(fmt.Sprintf(`unknown escape \%c`, c))
+		}
+		b.WriteString(e)
+	}
+	if i < len(s) {
+		i += 1 // skip the closing "
+	}
+	return b.String(), i, nil
+}
+
+// parse\" ( -- b u ; <string> ) parse a string with escapes up to the next "
+// into the transient string buffer
+func (f *Forth) _ParseEscaped() {
+	ntib := f.userAddr("#TIB")
+	in := f.userAddr(">IN")
+	tib := f.WordPtr(ntib + CELLL)
+	n := f.WordPtr(ntib)
+	i := f.WordPtr(in)
+	if i > n {
+		i = n
+	}
+	if !f.tibInMemory() {
+		return
+	}
+	s, used, err := unescape(string(f.Memory[tib+i : tib+n]))
+	if err != nil {
+		f.throwMessage(err.Error())
+		return
+	}
+	f.SetWordPtr(in, i+uint16(used))
+	b, err := f.transient(s)
+	if err != nil {
+		f.throwMessage(err.Error())
+		return
+	}
+	f.Push(b)
+	f.Push(uint16(len(s)))
+	f.Next()
+}
@@ -0,0 +1,72 @@
+package eforth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		s    string
+		good string
+		used int
+		ok   bool
+	}{
+		{`abc" rest`, "abc", 4, true},
+		{`a\tb\nc"`, "a\tb\nc", 8, true},
+		{`\q\"\\"`, `""\`, 7, true},
+		{`\x41\x7e"`, "A~", 9, true},
+		{`\m\z"`, "\r\n\x00", 5, true},
+		{`no end`, "no end", 6, true},
+		{`\x4"`, "", 0, false},
+		{`\y"`, "", 0, false},
+	}
+	for _, v := range tests {
+		s, used, err := unescape(v.s)
+		if (err == nil) != v.ok || v.ok && (s != v.good || used != v.used) {
+			t.Error(v.s, "unescaped to", s, used, err, "instead of", v.good, v.used)
+		}
+	}
+}
+
+func TestStringWords(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{`S" hello" TYPE`, "hello"},
+		{`: greet S" hi there" ; greet TYPE greet . DROP`, "hi there 8"},
+		{`: esc S\" a\tb\q" ; esc TYPE`, "a\tb\""},
+		{`S\" x\x41y" TYPE`, "xAy"},
+		{`S" abc" S" abd" COMPARE . S" abc" S" abc" COMPARE .`, " -1 0"},
+		{`S" abc" S" ab" COMPARE . S" ab" S" abc" COMPARE .`, " 1 -1"},
+		{`S" hello world" S" wor" SEARCH . TYPE`, " -1world"},
+		{`S" hello" S" xyz" SEARCH . TYPE`, " 0hello"},
+		{`S" hello" 2 /STRING TYPE`, "llo"},
+		{`: sl [ S" lit" ] SLITERAL ; sl TYPE`, "lit"},
+		{`PAD 3 BLANK PAD 3 + 1 CHAR x FILL PAD 4 TYPE`, "   x"},
+		{`S" abcd" PAD SWAP CMOVE PAD PAD 1 + 3 CMOVE> PAD 4 TYPE`, "aabc"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.HasPrefix(out, v.good) {
+			t.Errorf("%s should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestGoStrings(t *testing.T) {
+	f := New(nil, nil)
+	if err := f.PushString("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.PushString(" world"); err != nil {
+		t.Fatal(err)
+	}
+	w := f.PopString()
+	if h := f.PopString(); h+w != "hello world" {
+		t.Fatal("strings should round trip but got", h, w)
+	}
+	if err := f.PushString(strings.Repeat("x", STRBUFF+1)); err == nil {
+		t.Fatal("a string longer than the buffer should not fit")
+	}
+}
@@ -0,0 +1,196 @@
+package eforth
+
+import (
+	"fmt"
+)
+
+/*
+A task is a user area followed by its data, return and float stacks, made
+in the code dictionary by TASK.  The user area pointer UP tells which task
+runs, the 4 reserved cells at the start of every user area are used by the
+round robin multitasker:
+*/
+const (
+	TSTATUS  = 0 * CELLL  // 0 if the task is awake
+	TFOLLOW  = 1 * CELLL  // user area of the next task, 0 if there are no tasks
+	TRP      = 2 * CELLL  // saved return stack pointer
+	TFP0     = 3 * CELLL  // bottom of the float stack
+	TSTACKK  = 64 * CELLL // size of the data and of the return stack of a task
+	TASKSIZE = US + 2*TSTACKK + FSTACKK*FLOATT
+)
+
+func (f *Forth) addTasks() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"PAUSE", "PAUSE", f._Pause, 0},
+		{"(task)", "PTASK", f._Task, 0},
+		{"ACTIVATE", "ACTIV", f._Activate, COMPO},
+		{"STOP", "STOP", f._Stop, 0},
+		{"SLEEP", "SLEEP", f._Sleep, 0},
+		{"WAKE", "WAKE", f._Wake, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	f.prim2addr["TASKSIZE"] = TASKSIZE // a constant for the listing
+
+	err := f.WordFromASM(`
+
+;; Multitasking
+
+;   TASK	( -- ; <string> )
+;		Create a sleeping task. Its name returns the address of its user area.
+
+		$COLON	4,'TASK',TASK
+		DW	CREAT,HERE
+		DW	DOLIT,TASKSIZE,ALLOT
+		DW	PTASK,EXIT
+
+;   (done)	( -- )
+;		Where a task goes when the word that activated it returns.
+
+		$COLON	COMPO+6,'(done)',PDONE
+DONE1:		DW	STOP,BRAN,DONE1
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+// return the offset in the user area of the user variable called name
+func (f *Forth) userOffset(name string) uint16 {
+	ca, _ := f.Addr(name)
+	return f.WordPtr(ca + 3*CELLL)
+}
+
+/*
+Save the registers of the running task, find the next task that is awake and
+restore its registers.  The dictionary pointers are shared by all tasks, so
+they go along.  Returns false if there is no other task to run, or if go is
+running a word with run, which has to finish in the task it started in,
+or if a ! left a link or a saved RP outside Memory.
+*/
+func (f *Forth) switchTask() bool {
+	up, _ := f.Addr("UP")
+	up += 3 * CELLL
+	cur := f.WordPtr(up)
+	if f.nested > 0 || f.WordPtr(cur+TFOLLOW) == 0 {
+		return false
+	}
+	next := f.WordPtr(cur + TFOLLOW)
+	for next != cur && int(next)+US <= EM && f.WordPtr(next+TSTATUS) != 0 {
+		next = f.WordPtr(next + TFOLLOW)
+	}
+	if next == cur || int(next)+US > EM || int(f.WordPtr(next+TRP))+4*CELLL > EM {
+		return false
+	}
+	for _, r := range []uint16{f.IP, f.LP, f.FP, f.SP} {
+		f.RP -= CELLL
+		f.SetWordPtr(f.RP, r)
+	}
+	f.SetWordPtr(cur+TRP, f.RP)
+	f.SetWordPtr(cur+TFP0, f.fsp0)
+	for _, v := range []string{"CP", "NP", "LAST"} {
+		o := f.userOffset(v)
+		f.SetWordPtr(next+o, f.WordPtr(cur+o))
+	}
+	f.SetWordPtr(up, next)
+	f.RP = f.WordPtr(next + TRP)
+	for _, r := range []*uint16{&f.SP, &f.FP, &f.LP, &f.IP} {
+		*r = f.WordPtr(f.RP)
+		f.RP += CELLL
+	}
+	f.fsp0 = f.WordPtr(next + TFP0)
+	f.spLimit, f.rpLimit = next+US, f.WordPtr(next+f.userOffset("SP0"))
+	return true
+}
+
+// PAUSE ( -- ) let the other tasks that are awake run
+func (f *Forth) _Pause() {
+	f.switchTask()
+	f.Next()
+}
+
+// (task) ( a -- ) make a sleeping task with its user area at a and link it
+// after the running task
+func (f *Forth) _Task() {
+	a := f.Pop()
+	if !f.inMemory(a, TASKSIZE) {
+		return
+	}
+	up, _ := f.Addr("UP")
+	cur := f.WordPtr(up + 3*CELLL)
+	copy(f.Memory[a:a+US], f.Memory[cur:cur+US])
+	f.SetWordPtr(a+TSTATUS, asuint16(-1))
+	f.SetWordPtr(a+TRP, a+US+2*TSTACKK)
+	f.SetWordPtr(a+TFP0, a+TASKSIZE)
+	f.SetWordPtr(a+f.userOffset("SP0"), a+US+TSTACKK)
+	f.SetWordPtr(a+f.userOffset("RP0"), a+US+2*TSTACKK)
+	f.SetWordPtr(a+f.userOffset("HANDLER"), 0)
+	if f.WordPtr(cur+TFOLLOW) == 0 {
+		f.SetWordPtr(cur+TFOLLOW, cur)
+	}
+	f.SetWordPtr(a+TFOLLOW, f.WordPtr(cur+TFOLLOW))
+	f.SetWordPtr(cur+TFOLLOW, a)
+	f.Next()
+}
+
+// ACTIVATE ( a -- ) wake the task at a to run the rest of the current
+// definition with empty stacks, and return from the definition
+func (f *Forth) _Activate() {
+	a := f.Pop()
+	if !f.inMemory(a, US) {
+		return
+	}
+	done, _ := f.Addr("(done)")
+	rp := f.WordPtr(a + f.userOffset("RP0"))
+	if rp < 5*CELLL || !f.inMemory(rp-5*CELLL, 5*CELLL) {
+		f.throwMessage("invalid memory address")
+		return
+	}
+	frame := []uint16{done + 2*CELLL, f.IP, 0, f.WordPtr(a + TFP0), f.WordPtr(a + f.userOffset("SP0"))}
+	for _, r := range frame {
+		rp -= CELLL
+		f.SetWordPtr(rp, r)
+	}
+	f.SetWordPtr(a+TRP, rp)
+	f.SetWordPtr(a+f.userOffset("HANDLER"), 0)
+	f.SetWordPtr(a+TSTATUS, 0)
+	f.IP = f.WordPtr(f.RP)
+	f.RP += CELLL
+	f.Next()
+}
+
+// STOP ( -- ) put the running task to sleep and let the others run
+func (f *Forth) _Stop() {
+	up, _ := f.Addr("UP")
+	f.SetWordPtr(f.WordPtr(up+3*CELLL)+TSTATUS, asuint16(-1))
+	f.switchTask()
+	f.Next()
+}
+
+// SLEEP ( a -- ) put the task at a to sleep
+func (f *Forth) _Sleep() {
+	a := f.Pop()
+	if !f.inMemory(a+TSTATUS, CELLL) {
+		return
+	}
+	f.SetWordPtr(a+TSTATUS, asuint16(-1))
+	f.Next()
+}
+
+// WAKE ( a -- ) wake the task at a
+func (f *Forth) _Wake() {
+	a := f.Pop()
+	if !f.inMemory(a+TSTATUS, CELLL) {
+		return
+	}
+	f.SetWordPtr(a+TSTATUS, 0)
+	f.Next()
+}
@@ -0,0 +1,64 @@
+package eforth
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTasks(t *testing.T) {
+	def := "TASK t VARIABLE n 0 n !\r"
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{": go t ACTIVATE BEGIN 1 n +! PAUSE AGAIN ;\rgo PAUSE PAUSE PAUSE n @ .", "BYE 3"},
+		{": go t ACTIVATE BEGIN 1 n +! PAUSE AGAIN ;\rgo PAUSE t SLEEP PAUSE PAUSE n @ .", "BYE 1"},
+		{": go t ACTIVATE BEGIN 1 n +! STOP AGAIN ;\rgo PAUSE PAUSE t WAKE PAUSE n @ .", "BYE 2"},
+		{": go t ACTIVATE 5 n ! ;\rgo PAUSE PAUSE PAUSE n @ .", "BYE 5"},
+		{": go t ACTIVATE 1 2 3 PAUSE + + n ! ;\r7 go PAUSE PAUSE DEPTH . . n @ .", "BYE 1 7 6"},
+		{": go t ACTIVATE 1.5E0 PAUSE F>S n ! ;\rgo PAUSE FDEPTH . PAUSE n @ .", "BYE 0 1"},
+		{": l {: a :} PAUSE a n ! ;\r: go t ACTIVATE 4 l ;\rgo 9 l n @ .", "BYE 9"},
+		{": go t ACTIVATE HEX BASE @ n ! ;\rgo PAUSE 20 . n @ .", " 20 16"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(def + v.src); !strings.HasSuffix(out, v.good) {
+			t.Errorf("%q should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestTwoTasks(t *testing.T) {
+	src := "TASK a TASK b VARIABLE n 0 n !\r" +
+		": ga a ACTIVATE BEGIN 1 n +! PAUSE AGAIN ;\r" +
+		": gb b ACTIVATE BEGIN 10 n +! PAUSE AGAIN ;\r" +
+		"ga gb PAUSE PAUSE n @ ."
+	if out, _ := runForth(src); !strings.HasSuffix(out, " 22") {
+		t.Fatal("both tasks should run every PAUSE but printed", out)
+	}
+}
+
+// KEY has to let the other tasks run while it waits for input
+func TestKeyPauses(t *testing.T) {
+	r, w := io.Pipe()
+	o := new(bytes.Buffer)
+	f := New(r, o)
+	go w.Write([]byte("TASK t VARIABLE n 0 n !\r" +
+		": go t ACTIVATE BEGIN 1 n +! n @ 50 = UNTIL .\" done\" BYE ;\r" +
+		"go\r"))
+	done := make(chan bool)
+	go func() {
+		f.Main()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the task did not run while KEY waited")
+	}
+	if !strings.Contains(o.String(), "done") {
+		t.Fatal("the task should have printed done but the output was", o.String())
+	}
+}
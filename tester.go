@@ -0,0 +1,102 @@
+package eforth
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (f *Forth) addTester() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"T{", "TOPEN", f._TOpen, 0},
+		{"->", "TARROW", f._TArrow, 0},
+		{"}T", "TCLOSE", f._TClose, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Tester
+
+;   TESTING	( -- ; <string> )
+;		Name the tests that follow, the rest of the line is a comment.
+
+		$COLON	7,'TESTING',TESTI
+		DW	BKSLA,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+// T{ ( -- ) start a test, the results are what is put on the stack after it
+func (f *Forth) _TOpen() {
+	f.tsp = f.SP
+	f.Next()
+}
+
+// return the cells put on the stack since T{, deepest first, and drop them,
+// or false if some were taken
+func (f *Forth) testResults() ([]uint16, bool) {
+	if f.SP > f.tsp {
+		f.SP = f.tsp
+		return nil, false
+	}
+	var r []uint16
+	for a := f.tsp - CELLL; a >= f.SP && a < f.tsp; a -= CELLL {
+		r = append(r, f.WordPtr(a))
+	}
+	f.SP = f.tsp
+	return r, true
+}
+
+// -> ( ... -- ) keep the results of the test to compare them with those expected
+func (f *Forth) _TArrow() {
+	f.actual, f.actualOK = f.testResults()
+	f.Next()
+}
+
+// }T ( ... -- ) compare the results expected with those of the test
+func (f *Forth) _TClose() {
+	expected, ok := f.testResults()
+	switch {
+	case !ok || !f.actualOK || len(expected) != len(f.actual):
+		f.testFailure("WRONG NUMBER OF RESULTS")
+	default:
+		for i := range expected {
+			if expected[i] != f.actual[i] {
+				f.testFailure("INCORRECT RESULT")
+				break
+			}
+		}
+	}
+	f.actual = nil
+	f.Next()
+}
+
+// tell that the test in the current line failed, with the file and line
+func (f *Forth) testFailure(msg string) {
+	ntib := f.userAddr("#TIB")
+	tib, n := f.WordPtr(ntib+CELLL), f.WordPtr(ntib)
+	msg += ": " + strings.TrimSpace(f.StringAt(tib, n))
+	where := ""
+	if len(f.sources) > 0 {
+		s := f.sources[len(f.sources)-1]
+		where = fmt.Sprintf("%s:%d", s.name, s.line)
+	}
+	if f.TestFailed != nil {
+		f.TestFailed(where, msg)
+		return
+	}
+	if where != "" {
+		msg = where + ": " + msg
+	}
+	f.typeString("\r\n" + msg)
+}
@@ -0,0 +1,43 @@
+package eforth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTester(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"T{ 1 2 + -> 3 }T 5 .", " 5"},
+		{"T{ 1 2 + -> 4 }T", "INCORRECT RESULT: T{ 1 2 + -> 4 }T"},
+		{"T{ 1 2 -> 1 }T", "WRONG NUMBER OF RESULTS: T{ 1 2 -> 1 }T"},
+		{"9 T{ DROP -> }T .", "WRONG NUMBER OF RESULTS"},
+		{"9 T{ 1 -> 1 }T .", " 9"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.Contains(out, v.good) {
+			t.Errorf("%q should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+// a failing test tells TestFailed the file and line
+func TestTesterWhere(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "fail.fth")
+	os.WriteFile(p, []byte("TESTING failures\nT{ 1 -> 1 }T\nT{ 1 -> 2 }T\n"), 0644)
+	f := New(strings.NewReader(""), nil)
+	var failed []string
+	f.TestFailed = func(where, msg string) {
+		failed = append(failed, where+": "+msg)
+	}
+	if err := f.LoadFile(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0] != p+":3: INCORRECT RESULT: T{ 1 -> 2 }T" {
+		t.Error("one test should fail in line 3 but", failed)
+	}
+}
@@ -0,0 +1,170 @@
+package eforth
+
+import (
+	"time"
+)
+
+/*
+A Clock tells the time words what time it is.  The Forth uses RealClock
+unless its Clock is set.
+*/
+type Clock interface {
+	// the time when the Forth has run steps Steps
+	Now(steps uint64) time.Time
+	// wait d, for MS
+	Sleep(d time.Duration)
+}
+
+// the time of the computer
+type RealClock struct{}
+
+func (RealClock) Now(steps uint64) time.Time { return time.Now() }
+func (RealClock) Sleep(d time.Duration)      { time.Sleep(d) }
+
+/*
+A clock that starts at Start and advances PerStep for every Step and as much
+as MS waits, without waiting.  Forth code timed by it does the same every
+time it runs.  The zero VirtualClock stands still but for MS, so its TIMERs
+only run while MS waits.
+*/
+type VirtualClock struct {
+	Start   time.Time
+	PerStep time.Duration
+	slept   time.Duration
+}
+
+func (c *VirtualClock) Now(steps uint64) time.Time {
+	return c.Start.Add(time.Duration(steps)*c.PerStep + c.slept)
+}
+
+func (c *VirtualClock) Sleep(d time.Duration) { c.slept += d }
+
+// steps between the looks at the clock for timers that are due
+const TIMERSTEPS = 64
+
+// interrupt n raised every period by TIMER
+type timer struct {
+	n      int
+	period time.Duration
+	next   time.Time
+}
+
+func (f *Forth) addTime() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"MS", "MS", f._MS, 0},
+		{"TICKS", "TICKS", f._Ticks, 0},
+		{"UTIME", "UTIME", f._Utime, 0},
+		{"TIME&DATE", "TDATE", f._TimeDate, 0},
+		{"TIMER", "TIMER", f._Timer, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+}
+
+// return the time on the clock of f for a word, recorded or replayed
+func (f *Forth) now() time.Time {
+	if f.replay != nil {
+		return f.replayTime()
+	}
+	t := f.clock()
+	f.recordTime(t)
+	return t
+}
+
+// return the time on the clock of f
+func (f *Forth) clock() time.Time {
+	if f.Clock == nil {
+		return RealClock{}.Now(f.steps)
+	}
+	return f.Clock.Now(f.steps)
+}
+
+/*
+Raise the interrupts of the timers that are due.  Only the interrupts are
+recorded, a replay takes them from the record instead.
+*/
+func (f *Forth) tick() {
+	if f.replay != nil {
+		return
+	}
+	now := f.clock()
+	for i := range f.timers {
+		t := &f.timers[i]
+		if now.Before(t.next) {
+			continue
+		}
+		f.Interrupt(t.n)
+		t.next = t.next.Add(t.period)
+		if !now.Before(t.next) {
+			t.next = now.Add(t.period) // too late, skip the ticks missed
+		}
+	}
+}
+
+// MS ( u -- ) wait u milliseconds
+func (f *Forth) _MS() {
+	d := time.Duration(f.Pop()) * time.Millisecond
+	switch {
+	case f.replay != nil: // the times waited for are in the replay
+	case f.Clock == nil:
+		RealClock{}.Sleep(d)
+	default:
+		f.Clock.Sleep(d)
+	}
+	f.Next()
+}
+
+// TICKS ( -- u ) the low cell of the milliseconds since 1970
+func (f *Forth) _Ticks() {
+	f.Push(uint16(f.now().UnixMilli()))
+	f.Next()
+}
+
+// UTIME ( -- ud ) the low double of the microseconds since 1970
+func (f *Forth) _Utime() {
+	f.pushDouble(int32(uint32(f.now().UnixMicro())))
+	f.Next()
+}
+
+// TIME&DATE ( -- +n1 +n2 +n3 +n4 +n5 +n6 ) second, minute, hour, day, month and year
+func (f *Forth) _TimeDate() {
+	t := f.now()
+	for _, v := range []int{t.Second(), t.Minute(), t.Hour(), t.Day(), int(t.Month()), t.Year()} {
+		f.Push(uint16(v))
+	}
+	f.Next()
+}
+
+/*
+TIMER ( ca u n -- ) run the word at ca as interrupt n every u milliseconds,
+0 for u stops the timer.
+*/
+func (f *Forth) _Timer() {
+	n := asint16(f.Pop())
+	u := f.Pop()
+	ca := f.Pop()
+	if n < 0 || n >= INTERRUPTS {
+		f.throwMessage("interrupt number out of range")
+		return
+	}
+	f.SetWordPtr(f.vector(int(n)), ca)
+	timers := f.timers[:0]
+	for _, t := range f.timers {
+		if t.n != int(n) {
+			timers = append(timers, t)
+		}
+	}
+	if u != 0 {
+		period := time.Duration(u) * time.Millisecond
+		timers = append(timers, timer{int(n), period, f.now().Add(period)})
+	}
+	f.timers = timers
+	f.Next()
+}
@@ -0,0 +1,61 @@
+package eforth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+Run src with a virtual clock starting at 3:04:05 on 2 January 2024.  The
+keys are read when asked, so how many steps run does not depend on when a
+goroutine reads them.
+*/
+func clockForth(src string, perStep time.Duration) (string, *Forth) {
+	o := new(bytes.Buffer)
+	f := New(&pollKeys{keys: []byte(src + "\rBYE\r")}, o)
+	f.Clock = &VirtualClock{Start: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), PerStep: perStep}
+	f.Main()
+	return o.String(), f
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"TIME&DATE . . . . . .", " 2024 1 2 3 4 5 ok"},
+		{"TICKS 1000 MS TICKS SWAP - .", " 1000 ok"},
+		{"UTIME 5 MS UTIME 2SWAP D- D.", " 5000 ok"},
+		{"60000 MS TIME&DATE DROP DROP DROP . . .", " 3 5 5 ok"},
+	}
+	for _, v := range tests {
+		if out, _ := clockForth(v.src, 0); !strings.Contains(out, v.good) {
+			t.Errorf("%q should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestTimer(t *testing.T) {
+	src := "VARIABLE n 0 n ! : h 1 n +! ; : w BEGIN n @ 3 = UNTIL ;\r" +
+		"' h 10 3 TIMER w 0 0 3 TIMER 20 MS n @ . TICKS ."
+	out, f := clockForth(src, time.Microsecond)
+	if !strings.Contains(out, " 3 ") {
+		t.Fatal("the timer should run 3 times but printed", out)
+	}
+	// the virtual clock makes it run the same steps again
+	again, g := clockForth(src, time.Microsecond)
+	if again != out || g.steps != f.steps {
+		t.Errorf("printed %q in %d steps and then %q in %d steps", out, f.steps, again, g.steps)
+	}
+	if out, _ := clockForth("' DUP 1 16 TIMER", 0); !strings.Contains(out, "out of range") {
+		t.Error("TIMER should check the interrupt number but printed", out)
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	if out, _ := runForth("TICKS 20 MS TICKS SWAP - 20 < ."); !strings.HasSuffix(out, " 0") {
+		t.Error("20 MS should wait 20 milliseconds but printed", out)
+	}
+}
@@ -0,0 +1,239 @@
+package eforth
+
+import (
+	"fmt"
+)
+
+/*
+Constants, values and deferred words are colon words whose code is a run
+time word followed by a cell of data, like 2CONSTANT.  The run time word
+tells them apart for TO and SEE.
+*/
+func (f *Forth) addValues() {
+	words := []struct {
+		word  string
+		aword string
+		m     fn
+		flags int
+	}{
+		{"value!", "VALST", f._ValueStore, COMPO},
+		{"value+!", "VALPS", f._ValuePlusStore, COMPO},
+		{"local+!", "LOCPS", f._LocalPlusStore, COMPO},
+		{"(+to)", "PPTO", f._PlusTo, 0},
+	}
+	for _, v := range words {
+		f.AddPrim(v.word, v.m, v.flags)
+		f.asm2forth[v.aword] = v.word
+	}
+
+	err := f.WordFromASM(`
+
+;; Constants, values and deferred words
+
+;   >BODY	( ca -- a )
+;		Return the data field address of a word made by a defining word.
+
+		$COLON	5,'>BODY',TBODY
+		DW	DOLIT,6,PLUS,EXIT
+
+;   doCON	( -- w )
+;		Run time routine for CONSTANT.
+
+		$COLON	COMPO+5,'doCON',DOCON
+		DW	RFROM,AT,EXIT
+
+;   CONSTANT	( w -- ; <string> )
+;		Compile a new constant.
+
+		$COLON	8,'CONSTANT',CONST
+		DW	TOKEN,SNAME,OVERT
+		DW	DOLIT,DOLST,CALLC
+		DW	COMPI,DOCON,COMMA,EXIT
+
+;   doVAL	( -- w )
+;		Run time routine for VALUE.
+
+		$COLON	COMPO+5,'doVAL',DOVAL
+		DW	RFROM,AT,EXIT
+
+;   VALUE	( w -- ; <string> )
+;		Compile a new value, changed with TO and +TO.
+
+		$COLON	5,'VALUE',VALUE
+		DW	TOKEN,SNAME,OVERT
+		DW	DOLIT,DOLST,CALLC
+		DW	COMPI,DOVAL,COMMA,EXIT
+
+;   (defer)	( -- )
+;		Action of a deferred word that was not set.
+
+		$COLON	7,'(defer)',PDEFR
+		DW	DOLIT,-1
+		D$	ABORQ,' deferred word not set'
+		DW	EXIT
+
+;   doDEFER	( -- )
+;		Run time routine for DEFER. Execute the word stored in it.
+
+		$COLON	COMPO+7,'doDEFER',DODEF
+		DW	RFROM,AT,EXECU,EXIT
+
+;   DEFER	( -- ; <string> )
+;		Compile a new deferred word, set with IS.
+
+		$COLON	5,'DEFER',DEFER
+		DW	TOKEN,SNAME,OVERT
+		DW	DOLIT,DOLST,CALLC
+		DW	COMPI,DODEF,DOLIT,PDEFR,COMMA,EXIT
+
+;   DEFER@	( ca1 -- ca2 )
+;		Return the word the deferred word at ca1 executes.
+
+		$COLON	6,'DEFER@',DEFAT
+		DW	TBODY,AT,EXIT
+
+;   DEFER!	( ca2 ca1 -- )
+;		Make the deferred word at ca1 execute ca2.
+
+		$COLON	6,'DEFER!',DEFST
+		DW	TBODY,STORE,EXIT
+
+;   IS		( ca -- ; <string> )
+;		Make the deferred word named next execute ca.
+
+		$COLON	IMEDD+2,'IS',ISS
+		DW	TICK,TEVAL,AT,DOLIT,INTER,EQUAL
+		DW	QBRAN,ISS1
+		DW	DEFST,EXIT
+ISS1:		DW	LITER,COMPI,DEFST,EXIT
+
+;   ACTION-OF	( -- ca | ; <string> )
+;		Return the word the deferred word named next executes.
+
+		$COLON	IMEDD+9,'ACTION-OF',ACTOF
+		DW	TICK,TEVAL,AT,DOLIT,INTER,EQUAL
+		DW	QBRAN,ACTO1
+		DW	DEFAT,EXIT
+ACTO1:		DW	LITER,COMPI,DEFAT,EXIT
+
+;   +TO		( w -- ; <string> )
+;		Add w to the local or value named next.
+
+		$COLON	IMEDD+3,'+TO',PLTO
+		DW	TOKEN,PPTO,EXIT
+`)
+	if err != nil {
+		fmt.Println("ERROR: ", err)
+	}
+}
+
+// return true if the text interpreter is compiling
+func (f *Forth) compiling() bool {
+	inter, _ := f.Addr("$INTERPRET")
+	return f.WordPtr(f.userAddr("'EVAL")) != inter
+}
+
+// return true if ca is a word made by VALUE
+func (f *Forth) isValue(ca uint16) bool {
+	dolist, _ := f.Addr("doLIST")
+	doval, _ := f.Addr("doVAL")
+	return int(ca)+3*CELLL <= EM && f.WordPtr(ca) == CALLL &&
+		f.WordPtr(ca+CELLL) == dolist && f.WordPtr(ca+2*CELLL) == doval
+}
+
+/*
+Store or with plus add the top of the stack to the local or value named by
+the counted string at a.  When compiling the store is compiled instead.
+*/
+func (f *Forth) to(a uint16, plus bool) {
+	compiling := f.compiling()
+	words := map[bool][]string{
+		false: {"local!", "value!"},
+		true:  {"local+!", "value+!"},
+	}[plus]
+	if i := f.localIndex(f.countedString(a)); compiling && f.locals != nil && i >= 0 {
+		ca, _ := f.Addr(words[0])
+		err := f.comma(ca)
+		if err == nil {
+			err = f.comma(uint16(i))
+		}
+		if err != nil {
+			f.throwMessage(err.Error())
+			return
+		}
+		f.Next()
+		return
+	}
+	nameq, _ := f.Addr("NAME?")
+	f.Push(a)
+	f.run(nameq)
+	if f.Pop() == 0 {
+		f.throw(f.Pop())
+		return
+	}
+	ca := f.Pop()
+	if !f.isValue(ca) {
+		f.throwMessage(f.countedString(a) + " is not a VALUE")
+		return
+	}
+	if compiling {
+		w, _ := f.Addr(words[1])
+		err := f.comma(w)
+		if err == nil {
+			err = f.comma(ca)
+		}
+		if err != nil {
+			f.throwMessage(err.Error())
+			return
+		}
+	} else if plus {
+		f.SetWordPtr(ca+3*CELLL, f.WordPtr(ca+3*CELLL)+f.Pop())
+	} else {
+		f.SetWordPtr(ca+3*CELLL, f.Pop())
+	}
+	f.Next()
+}
+
+// (to) ( w a -- ) store w into the local or value named at a
+func (f *Forth) _To() {
+	f.to(f.Pop(), false)
+}
+
+// (+to) ( w a -- ) add w to the local or value named at a
+func (f *Forth) _PlusTo() {
+	f.to(f.Pop(), true)
+}
+
+// value! ( w -- ) store into the value whose code address is inline
+func (f *Forth) _ValueStore() {
+	ca := f.WordPtr(f.IP)
+	f.IP += CELLL
+	if !f.inMemory(ca+3*CELLL, CELLL) {
+		return
+	}
+	f.SetWordPtr(ca+3*CELLL, f.Pop())
+	f.Next()
+}
+
+// value+! ( w -- ) add to the value whose code address is inline
+func (f *Forth) _ValuePlusStore() {
+	ca := f.WordPtr(f.IP)
+	f.IP += CELLL
+	if !f.inMemory(ca+3*CELLL, CELLL) {
+		return
+	}
+	f.SetWordPtr(ca+3*CELLL, f.WordPtr(ca+3*CELLL)+f.Pop())
+	f.Next()
+}
+
+// local+! ( w -- ) add to the local whose index is inline
+func (f *Forth) _LocalPlusStore() {
+	i := f.WordPtr(f.IP)
+	f.IP += CELLL
+	a := f.LP - CELLL*(i+1)
+	if !f.inMemory(a, CELLL) {
+		return
+	}
+	f.SetWordPtr(a, f.WordPtr(a)+f.Pop())
+	f.Next()
+}
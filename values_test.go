@@ -0,0 +1,76 @@
+package eforth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"42 CONSTANT k k .", " 42"},
+		{"5 VALUE v v . 7 TO v v . 3 +TO v v .", " 5 7 10"},
+		{"1 VALUE w : setw TO w ; : addw +TO w ; 9 setw w . 2 addw w .", " 9 11"},
+		{": lp {: a :} 5 +TO a a ; 1 lp .", " 6"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.HasPrefix(out, v.good) {
+			t.Errorf("%s should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"DEFER d ' DUP IS d 3 d . .", " 3 3"},
+		{"DEFER d : s IS d ; ' DROP s 1 2 d .", " 1"},
+		{"DEFER d ' DUP IS d ACTION-OF d ' DUP = .", " -1"},
+		{"DEFER d : a ACTION-OF d ; ' SWAP IS d a ' SWAP = .", " -1"},
+		{"DEFER d ' OVER ' d DEFER! ' d DEFER@ ' OVER = .", " -1"},
+		{"CREATE c ' c >BODY HERE = .", " -1"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.HasPrefix(out, v.good) {
+			t.Errorf("%s should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestValueErrors(t *testing.T) {
+	for _, v := range []struct {
+		src  string
+		good string
+	}{
+		{"DEFER d\rd", "deferred word not set"},
+		{"5 TO DUP", "DUP is not a VALUE"},
+		{"5 TO nosuch", "nosuch nosuch ?"},
+	} {
+		if out, _ := runForth(v.src + "\r"); !strings.Contains(out, v.good) {
+			t.Errorf("%s should fail with %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
+
+func TestSeeValues(t *testing.T) {
+	tests := []struct {
+		src  string
+		good string
+	}{
+		{"-3 CONSTANT k SEE k", "-3 CONSTANT k"},
+		{"4 VALUE v SEE v", "4 VALUE v"},
+		{"DEFER d SEE d", "DEFER d"},
+		{"DEFER d ' DUP IS d SEE d", "DEFER d ' DUP IS d"},
+		{"0 VALUE v : s 1 TO v 2 +TO v ; SEE s", ": s 1 TO v 2 +TO v ;"},
+		{": l {: a :} 1 +TO a ; SEE l", ": l {: a :} 1 +TO a ;"},
+	}
+	for _, v := range tests {
+		if out, _ := runForth(v.src); !strings.Contains(out, v.good) {
+			t.Errorf("%s should print %q but printed %q", v.src, v.good, out)
+		}
+	}
+}
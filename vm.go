@@ -19,7 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strings"
+	"os"
+	"sync/atomic"
 )
 
 /*
@@ -126,6 +127,14 @@ func (v *word) unsigned() uint {
 
 }
 
+/*
+A Forth is one eForth machine with its own memory, registers and
+dictionaries.  Instances share no state they change, the maps of names a
+Clone shares are copied before either one changes them, so any number of
+them can be made and run in parallel goroutines.  A single Forth is not
+safe for concurrent use, its methods have to be called from one goroutine
+at a time, and not while Main runs it.
+*/
 type Forth struct {
 	/*
 
@@ -144,11 +153,14 @@ type Forth struct {
 	RP  uint16
 	WP  uint16
 	aWP uint16
+	FP  uint16 // float stack pointer, see addFloat
+	LP  uint16 // locals frame pointer, see addLocals
 
 	Input  io.Reader
 	Output io.Writer
 
 	rxchan chan uint16
+	rxquit chan struct{} // closed to stop the reader started by !IO
 
 	Memory [EM]byte
 
@@ -175,20 +187,99 @@ type Forth struct {
 	addr2word  map[uint16]string
 	prim2func  map[string]fn
 	pcode2word map[uint16]string
+	asm2forth  map[string]string // labels of the listings to forth names
+	hostPrims  map[string]bool   // primitives added with AddPrim after New
+	bound      map[string]binding // go functions added with Bind
+
+	built   bool // the primitives and listings were all added
+	binding bool // only binding the primitives to this instance, see Clone
+	shared  bool // the maps are shared with a Clone, see own
 
 	_LAST uint16 // last name in name dictionary
 	_NP   uint16 // bottom of name dictionary
 
 	_USER  uint16        // first user variable offset
 	macros map[string]fn // need this for hardcoding "_USER = ..." for #TIB, CONTEXT, and CURRENT user vars
+
+	booted bool // the user area was initialized by boot, see LoadFile
+
+	/*
+	   Nested input sources for INCLUDED and LoadFile.
+	*/
+	sources  []*source
+	linebuf  uint16 // LINEE bytes holding the current line of an included file
+	errbuf   uint16 // ERRBUFF bytes for counted string error messages raised from go
+	errwhere bool   // errbuf already tells the file and line of the error
+
+	blockFile *os.File
+	buffers   []blockBuffer
+	lastbuf   int // index of the buffer used last, for UPDATE
+
+	fsp0      uint16 // bottom of the float stack, growing downward
+	precision int    // significant digits displayed by F. FE. FS.
+
+	strbuf  uint16 // STRBUFF bytes of transient strings for S\" and PushString
+	strnext int    // offset of the next free byte in strbuf
+
+	locals []string // names of the locals of the definition being compiled
+
+	nested int // depth of run, tasks are not switched while go runs a word
+
+	effects map[uint16]string // stack comments of the words, see CheckEffects
+
+	spLimit uint16 // the data stack of the running task overflows below it
+	rpLimit uint16 // and its return stack below this
+
+	StepBudget uint64 // the most Steps a Call may run, 0 for no limit
+	steps      uint64 // Steps run so far
+	limit      uint64 // steps where run stops, 0 for none, see Call
+
+	pending uint32 // interrupts raised by Interrupt and not yet latched
+	stopped uint32 // set by Stop, Step runs nothing more
+	irq     uint32 // interrupts latched and not yet taken
+	masked  bool   // interrupts are held by DI
+	irqRP   uint16 // RP of the saved IP and WP while a handler runs, or 0
+
+	Clock  Clock   // for the time words, RealClock if nil
+	timers []timer // started by TIMER
+
+	polls  uint64    // ?RX run so far
+	record *recorder // see Record
+	replay *replayer // see Replay
+
+	cover []uint32 // times each cell ran, see StartCoverage
+	arms  []uint8  // ways each conditional branch went
+
+	tsp        uint16                  // SP at T{
+	actual     []uint16                // results of the test before ->
+	actualOK   bool                    // no cells were taken before ->
+	TestFailed func(where, msg string) // told of a failed T{ }T instead of displaying it
 }
 
 func (f *Forth) newWord(name string, startaddr uint16, bitmask int) {
+	f.own()
 	f.addr2word[startaddr] = name
 	f.prim2addr[name] = startaddr
 	f.addName(name, startaddr, bitmask)
 }
 
+/*
+Make the colon definition word call the latest definition of name where it
+calls an older one, for words redefined to do more than the kernel's.
+*/
+func (f *Forth) callLatest(word, name string) {
+	if f.binding {
+		return
+	}
+	ca, _ := f.Addr(word)
+	to, _ := f.Addr(name)
+	for _, in := range f.instructions(ca + 2*CELLL) {
+		if in.name == name {
+			f.SetWordPtr(in.a, to)
+		}
+	}
+}
+
 type fn func()
 
 func wordptr(mem []byte, reg uint16) (res uint16) {
@@ -201,23 +292,54 @@ func setwordptr(mem []byte, reg, value uint16) {
 }
 
 /*
-Return a new forth instance using reader and writer as input and output
+Return a new forth instance using reader and writer as input and output.
+It is a Clone of a base instance built the first time New is called.
 */
 func New(r io.Reader, w io.Writer) *Forth {
-	f := &Forth{SP: SPP, RP: RPP,
+	f := baseImage().Clone()
+	f.Input = r
+	f.Output = w
+	return f
+}
+
+// build a new forth instance from the primitives and listings
+func build() *Forth {
+	f := &Forth{SP: SPP, RP: RPP, spLimit: UPP + US, rpLimit: SPP,
 		prim2addr:  make(map[string]uint16),
 		addr2word:  make(map[uint16]string),
 		prim2func:  make(map[string]fn),
 		pcode2word: make(map[uint16]string),
-		_NP:         NAMEE,
-		_LAST:       0,
+		asm2forth:  make(map[string]string),
+		hostPrims:  make(map[string]bool),
+		effects:    make(map[uint16]string),
+		_NP:        NAMEE,
+		_LAST:      0,
 		_USER:      4 * CELLL,
-		Input:      r,
-		Output:     w,
 	}
+	f.addWords()
+	f.doUserVariables() // COLD has to know the words added after the last listing
+	f.built = true
+	return f
+}
+
+func (f *Forth) addWords() {
 	f.addPrimitives()
 	f.addHiforth()
-	return f
+	f.addInclude()
+	f.addBlocks()
+	f.addDouble()
+	f.addFloat()
+	f.addString()
+	f.addLocals()
+	f.addLoops()
+	f.addValues()
+	f.addTasks()
+	f.addSee()
+	f.addCompiler()
+	f.addInterrupts()
+	f.addTime()
+	f.addTester()
+	f.addDictionary()
 }
 
 func (f *Forth) addName(word string, addr uint16, bitmask int) {
@@ -239,6 +361,32 @@ func (f *Forth) addName(word string, addr uint16, bitmask int) {
 
 }
 
+/*
+Return the names in the dictionary, the latest first, walking the link
+fields from LAST.  A name defined again is returned once.
+*/
+func (f *Forth) Words() []string {
+	na := f._LAST
+	if f.booted {
+		na = f.WordPtr(f.userAddr("LAST"))
+	}
+	var words []string
+	seen := make(map[string]bool)
+	for na != 0 && int(na) < EM {
+		n := int(f.Memory[na] & 0x1F) // without the lexicon bits
+		if int(na)+1+n > EM {
+			break
+		}
+		w := string(f.Memory[na+1 : int(na)+1+n])
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+		na = f.WordPtr(na - CELLL)
+	}
+	return words
+}
+
 /* 
 For adding primitives to forth defined by go functions
 for example to define bar
@@ -250,6 +398,15 @@ f.AddPrim("doten", func() {
 most primtives need Next to advance the instruction and work pointers
 */
 func (f *Forth) AddPrim(word string, m fn, flags int) {
+	if f.binding {
+		f.prim2func[word] = m
+		return
+	}
+	f.own()
+	if f.built {
+		f.hostPrims[word] = true
+		f.beginWord()
+	}
 	f.prims = f.prims + 1
 	addr := CODEE + (2 * (f.prims - 1))
 	f.prim2addr[word] = addr
@@ -258,38 +415,20 @@ func (f *Forth) AddPrim(word string, m fn, flags int) {
 	//fmt.Printf("%x is \"%s\"\n", f.prims, word)
 	f.SetWordPtr(addr, f.prims)
 	f.newWord(word, addr, flags)
-}
-
-func (f *Forth) removeComments(a string) (b string) {
-	b = a
-	i := strings.Index(a, "(")
-	if i == -1 {
-		return
-	} else {
-		j := strings.Index(a, ")")
-		b = a[:i] + a[j+1:]
+	if f.built {
+		f.endWord()
 	}
-	return
-}
-
-func (f *Forth) addWord(name string, words ...string) error {
-	a := append([]string{"CALLL", "doLIST"}, words...)
-	a = append(a, "EXIT")
-	err := f.compileWords(name, a, nil, 0)
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
 /*
    Use this for adding high level colon definitions in forth
    for example: f.AddWord(": z FOR .S NEXT ;") will add the z word.
+   Control structures, number and string literals, ( and \ comments and
+   IMMEDIATE after the ; are compiled as : would, an error tells the line
+   and word that could not be compiled.
 */
 func (f *Forth) AddWord(cdef string) (e error) {
-	e = nil
-	all := strings.Fields(f.removeComments(cdef))
-	e = f.addWord(all[1], all[2:len(all)-1]...)
+	e = f.compileDefinition(cdef)
 	return
 }
 
@@ -351,8 +490,19 @@ func (f *Forth) showstacks() {
 
 /*
 Calls setup and then Steps until it's time to exit.
+
+If the system was already booted by LoadFile or an earlier Main the user
+area is saved as the cold start values first, so COLD keeps everything that
+was loaded.  When Main returns the goroutine reading Input stops after the
+Read it may be blocked in, and the record is flushed.
 */
 func (f *Forth) Main() {
+	defer f.flushRecord()
+	if f.booted {
+		n, _ := f.Addr("ULAST-UZERO")
+		copy(f.Memory[0:n], f.Memory[UPP:UPP+n])
+	}
+	f.booted = true // COLD initializes the user area
 	if e := f.setupIP(); e != nil {
 		fmt.Println(e)
 		return
@@ -363,6 +513,241 @@ inf:
 			break inf
 		}
 	}
+	if f.rxquit != nil {
+		close(f.rxquit)
+		f.rxquit = nil
+	}
+}
+
+/*
+Leave out the sign on message and the ok prompts of Main, errors are still
+displayed.
+*/
+func (f *Forth) Quiet() {
+	// boot with !IO alone instead of hi
+	boot, _ := f.Addr("'BOOT")
+	stoio, _ := f.Addr("!IO")
+	f.SetWordPtr(boot+3*CELLL, stoio)
+	// make .OK branch over its ' ok' to the CR
+	ok, _ := f.Addr(".OK")
+	branch, _ := f.Addr("branch")
+	f.SetWordPtr(ok+2*CELLL, branch)
+	f.SetWordPtr(ok+3*CELLL, f.WordPtr(ok+8*CELLL))
+}
+
+/*
+Initialize the user area, stacks and search order the way COLD does, but
+without running 'BOOT or QUIT, so go code can use the interpreter before
+(or instead of) calling Main.
+*/
+func (f *Forth) boot() {
+	if f.booted {
+		return
+	}
+	f.booted = true
+	f.doUserVariables()
+	n, _ := f.Addr("ULAST-UZERO")
+	copy(f.Memory[UPP:UPP+n], f.Memory[0:n])
+	for _, w := range []string{"PRESET", "FORTH"} {
+		ca, _ := f.Addr(w)
+		f.run(ca)
+	}
+	context := f.WordPtr(f.userAddr("CONTEXT"))
+	current := f.userAddr("CURRENT")
+	f.SetWordPtr(current, context)
+	f.SetWordPtr(current+CELLL, context)
+	ca, _ := f.Addr("OVERT")
+	f.run(ca)
+}
+
+/*
+Run the word at code address ca until it returns to the caller.  The
+registers are saved and restored so this works from inside a primitive.
+
+Address 0 holds the cold start user area and never code, so it is used as
+the return address of ca.  Returns false if BYE was executed.
+
+The word must not THROW past this call, see catch.  PAUSE does not switch
+tasks until it returns, and it stops like BYE at the step limit of Call.
+*/
+func (f *Forth) run(ca uint16) bool {
+	ip, wp, awp, rp := f.IP, f.WP, f.aWP, f.RP
+	f.nested += 1
+	defer func() { f.nested -= 1 }()
+	f.IP = 0
+	f.WP = ca
+	f.aWP = ca
+	for f.aWP != 0 || f.RP != rp {
+		if f.limit != 0 && f.steps >= f.limit {
+			return false
+		}
+		if !f.Step() {
+			return false
+		}
+	}
+	f.IP, f.WP, f.aWP = ip, wp, awp
+	return true
+}
+
+/*
+Run the word at code address ca with CATCH and return the error it threw or 0.
+*/
+func (f *Forth) catch(ca uint16) (err uint16, ok bool) {
+	c, _ := f.Addr("CATCH")
+	f.Push(ca)
+	if !f.run(c) {
+		return 0, false
+	}
+	return f.Pop(), true
+}
+
+/*
+Make the next Step run THROW with err on the data stack.  Primitives that
+call this must not call Next.
+*/
+func (f *Forth) throw(err uint16) {
+	f.Push(err)
+	f.WP, _ = f.Addr("THROW")
+}
+
+/*
+THROW a counted string error message.  QUIT displays it as "msg ? ".
+*/
+func (f *Forth) throwMessage(msg string) {
+	if len(msg) > ERRBUFF-1 {
+		msg = msg[:ERRBUFF-1]
+	}
+	f.setCountedString(f.errbuf, msg)
+	f.errwhere = false
+	f.throw(f.errbuf)
+}
+
+/*
+Return true when the n bytes at a are all in Memory, otherwise THROW an
+invalid memory address.  Primitives that get false must not call Next.
+*/
+func (f *Forth) inMemory(a uint16, n int) bool {
+	if n >= 0 && int(a)+n <= EM {
+		return true
+	}
+	f.throwMessage("invalid memory address")
+	return false
+}
+
+/*
+THROW a stack overflow before the data or return stack grows over the user
+area or the other stack, or an underflow when one is popped above the top
+of Memory's stacks.  THROW starts from the CATCH frame, the cells above it
+are kept.
+*/
+func (f *Forth) stackError() {
+	msg := "stack overflow"
+	switch {
+	case f.RP < f.rpLimit:
+		msg = "return stack overflow"
+	case f.SP > RPP:
+		msg = "stack underflow"
+	case f.RP > RPP:
+		msg = "return stack underflow"
+	}
+	f.SP = f.WordPtr(f.userAddr("SP0"))
+	if frame := f.WordPtr(f.userAddr("HANDLER")); frame != 0 {
+		f.RP = frame
+	} else {
+		f.RP = f.WordPtr(f.userAddr("RP0"))
+	}
+	f.throwMessage(msg)
+}
+
+/*
+Return the string counted by the byte at a.
+*/
+func (f *Forth) countedString(a uint16) string {
+	if int(a) >= EM {
+		return ""
+	}
+	n := int(f.Memory[a])
+	if int(a)+1+n > EM {
+		return ""
+	}
+	return string(f.Memory[int(a)+1 : int(a)+1+n])
+}
+
+func (f *Forth) setCountedString(a uint16, s string) {
+	f.Memory[a] = byte(len(s))
+	copy(f.Memory[a+1:], s)
+}
+
+/*
+Return the address of the user variable called name in the current user area.
+*/
+func (f *Forth) userAddr(name string) uint16 {
+	up, _ := f.Addr("UP")
+	ca, _ := f.Addr(name)
+	return f.WordPtr(up+3*CELLL) + f.WordPtr(ca+3*CELLL)
+}
+
+/*
+Return the address of the data of a word defined with doVAR, like SCR.
+*/
+func (f *Forth) varAddr(name string) uint16 {
+	ca, _ := f.Addr(name)
+	return ca + 3*CELLL
+}
+
+/*
+Reserve n bytes in the code dictionary and return their address.  Before
+boot this moves the cold start CP, afterwards the CP of the user area.
+*/
+func (f *Forth) allot(n uint16) (uint16, error) {
+	if f.binding {
+		return 0, nil
+	}
+	n = (n + CELLL - 1) / CELLL * CELLL
+	if f.booted {
+		cp := f.userAddr("CP")
+		a := f.WordPtr(cp)
+		if int(a)+int(n) > int(f.WordPtr(f.userAddr("NP"))) {
+			return 0, errors.New(fmt.Sprintf("no room for %d bytes in the dictionary", n))
+		}
+		f.SetWordPtr(cp, a+n)
+		return a, nil
+	}
+	a := CODEE + CELLL*f.prims
+	if int(a)+int(n) > int(f._NP) {
+		return 0, errors.New(fmt.Sprintf("no room for %d bytes in the dictionary", n))
+	}
+	f.prims += n / CELLL
+	f.doUserVariables()
+	return a, nil
+}
+
+/*
+Compile v to the code dictionary.
+*/
+func (f *Forth) comma(v uint16) error {
+	a, err := f.allot(CELLL)
+	if err != nil {
+		return err
+	}
+	f.SetWordPtr(a, v)
+	return nil
+}
+
+/*
+Display s with TYPE, so it goes where the rest of the output goes.
+*/
+func (f *Forth) typeString(s string) {
+	ca, _ := f.Addr("TYPE")
+	for len(s) > 0 {
+		n := len(s)
+		if n > STRBUFF/2 {
+			n = STRBUFF / 2
+		}
+		f.PushString(s[:n])
+		f.run(ca)
+		s = s[n:]
+	}
 }
 
 /*
@@ -373,6 +758,25 @@ func (f *Forth) Step() bool {
 	if debug {
 		fmt.Printf("&WP %x WP %x IP %x", f.aWP, f.WP, f.IP)
 	}
+	if atomic.LoadUint32(&f.stopped) != 0 {
+		return false
+	}
+	f.steps++
+	if len(f.timers) > 0 && f.steps%TIMERSTEPS == 0 {
+		f.tick()
+	}
+	if f.replay != nil {
+		f.replayInterrupts()
+	} else if atomic.LoadUint32(&f.pending) != 0 {
+		f.latch()
+	}
+	if f.irqRP != 0 || f.irq != 0 {
+		f.interrupt()
+	}
+	if int(f.IP)+CELLL > EM || int(f.WP)+CELLL > EM {
+		f.IP = 0 // THROW does not come back to it
+		f.throwMessage("invalid memory address")
+	}
 	// simulate JMP to f.WP
 	pcode := f.WordPtr(f.WP)
 	word := f.Frompcode(pcode)
@@ -386,17 +790,32 @@ func (f *Forth) Step() bool {
 		f.showstacks()
 		//fmt.Println(dumpmem(f, f._LAST-10, 20))
 	}
+	a := f.aWP
 	err := f._CallFn(word)
 	if err != nil {
 		fmt.Println(err)
 		return false
 	}
+	if f.SP < f.spLimit || f.RP < f.rpLimit || f.SP > RPP || f.RP > RPP {
+		f.stackError()
+	}
+	if f.arms != nil && conditional[word] {
+		f.coverBranch(a)
+	}
 	if f.IP == 0xffff { // for BYE
 		return false
 	}
 	return true
 }
 
+/*
+Make Main, or a word run from go, end before its next Step.  It may be
+called from any goroutine, to end a Forth that runs a word forever.
+*/
+func (f *Forth) Stop() {
+	atomic.StoreUint32(&f.stopped, 1)
+}
+
 /*
 Get the word pointed to by reg
 */
@@ -426,6 +845,14 @@ lodsw
 jmp ax
 */
 func (f *Forth) Next() {
+	if int(f.IP)+CELLL > EM {
+		f.IP = 0 // THROW does not come back to it
+		f.throwMessage("invalid memory address")
+		return
+	}
+	if f.cover != nil {
+		f.cover[f.IP/CELLL]++
+	}
 	f.aWP = f.IP
 	f.WP = f.WordPtr(f.IP)
 	f.IP += 2
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestWordSize(t *testing.T) {
@@ -500,3 +501,36 @@ func TestBeginWhileRepeat(t *testing.T) {
 		t.Fatal("should have left 30 on the stack")
 	}
 }
+
+func TestWordsList(t *testing.T) {
+	f := New(nil, new(bytes.Buffer))
+	words := f.Words()
+	last := string(f.Memory[f._LAST+1 : f._LAST+1+uint16(f.Memory[f._LAST]&0x1F)])
+	if len(words) == 0 || words[0] != last || words[len(words)-1] != "BYE" {
+		t.Fatal("should list the dictionary from the last name but listed", words)
+	}
+	f.AddWord(": sq DUP * ;")
+	if err := f.Include("", strings.NewReader(": cube DUP sq * ; : sq 1 ;")); err != nil {
+		t.Fatal(err)
+	}
+	words = f.Words()
+	if words[0] != "sq" || words[1] != "cube" || words[2] == "sq" {
+		t.Fatal("should list sq once and the latest first but listed", words[:3])
+	}
+}
+
+func TestStop(t *testing.T) {
+	_, f := NewForth(": x BEGIN AGAIN ; x\r")
+	done := make(chan bool)
+	go func() {
+		f.Main()
+		done <- true
+	}()
+	time.Sleep(50 * time.Millisecond)
+	f.Stop()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Main should end after Stop")
+	}
+}